@@ -0,0 +1,51 @@
+package edgetts
+
+import (
+	"time"
+
+	"github.com/lib-x/edgetts/internal/communicate"
+)
+
+// BoundaryKind identifies the unit of text a Boundary marks.
+type BoundaryKind int
+
+const (
+	// BoundaryWord marks a single spoken word.
+	BoundaryWord BoundaryKind = iota
+	// BoundarySentence marks a whole sentence; requires WithSentenceBoundaries.
+	BoundarySentence
+)
+
+// Boundary reports when a piece of the input is spoken in the synthesized audio.
+type Boundary struct {
+	Kind BoundaryKind
+	// Offset is the position of the text relative to the start of the audio.
+	Offset time.Duration
+	// Duration is how long the text takes to speak.
+	Duration time.Duration
+	// Text is the spoken text as reported by the service.
+	Text string
+}
+
+// End returns the position in the audio where the text stops being spoken.
+func (b Boundary) End() time.Duration {
+	return b.Offset + b.Duration
+}
+
+// boundaryFromInternal converts a service boundary measured in 100ns ticks.
+func boundaryFromInternal(b communicate.Boundary) Boundary {
+	kind := BoundaryWord
+	if b.Type == "SentenceBoundary" {
+		kind = BoundarySentence
+	}
+	return Boundary{
+		Kind:     kind,
+		Offset:   ticksToDuration(b.Offset),
+		Duration: ticksToDuration(b.Duration),
+		Text:     b.Text,
+	}
+}
+
+func ticksToDuration(ticks int) time.Duration {
+	return time.Duration(ticks) * 100 * time.Nanosecond
+}
@@ -0,0 +1,45 @@
+package edgetts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts/internal/communicate"
+)
+
+func TestBoundaryFromInternal(t *testing.T) {
+	got := boundaryFromInternal(communicate.Boundary{
+		Type:     "WordBoundary",
+		Offset:   1_000_000,
+		Duration: 2_500_000,
+		Text:     "hello",
+	})
+	if got.Kind != BoundaryWord || got.Text != "hello" {
+		t.Fatalf("unexpected boundary: %+v", got)
+	}
+	if got.Offset != 100*time.Millisecond || got.Duration != 250*time.Millisecond {
+		t.Fatalf("unexpected timing: %+v", got)
+	}
+	if got.End() != 350*time.Millisecond {
+		t.Fatalf("unexpected end: %v", got.End())
+	}
+}
+
+func TestBoundaryFromInternalSentence(t *testing.T) {
+	got := boundaryFromInternal(communicate.Boundary{Type: "SentenceBoundary", Text: "Hello world."})
+	if got.Kind != BoundarySentence {
+		t.Fatalf("expected sentence boundary, got %+v", got)
+	}
+}
+
+func TestBoundariesEmptyInput(t *testing.T) {
+	client := New()
+	if _, _, err := client.DoWithBoundaries(context.Background(), Text("")); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected ErrEmptyInput, got %v", err)
+	}
+	if _, _, err := client.StreamWithBoundaries(context.Background(), Text(" ")); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected ErrEmptyInput from stream, got %v", err)
+	}
+}
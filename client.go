@@ -12,6 +12,8 @@ import (
 	"strings"
 
 	"github.com/lib-x/edgetts/internal/communicate"
+	"github.com/lib-x/edgetts/internal/communicateOption"
+	"github.com/lib-x/edgetts/internal/validate"
 )
 
 // Client synthesizes text and SSML to audio.
@@ -22,14 +24,37 @@ type Client struct {
 
 // New creates a reusable client.
 func New(opts ...Option) *Client {
-	return &Client{options: append([]Option(nil), opts...), vm: NewVoiceManager()}
+	c := &Client{options: append([]Option(nil), opts...)}
+	c.vm = voiceManagerOf(c.mergeOptions())
+	return c
+}
+
+// NewClient creates a reusable client like New, but checks the client options first:
+// a malformed proxy, voice, pitch, rate, volume or output format, or network options
+// that cannot apply to the client of WithHTTPClient, are reported here instead of by
+// every request.
+func NewClient(opts ...Option) (*Client, error) {
+	c := New(opts...)
+	opt := c.mergeOptions().toInternalOption()
+	opt.CheckAndApplyDefaultOption()
+	if err := validate.WithCommunicateOption(opt); err != nil {
+		return nil, err
+	}
+	if _, err := communicate.NewHTTPClient(opt); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 // NewSpeech creates a compatibility wrapper around the new client-based API.
 //
 // Deprecated: prefer New and the package-level helper functions.
 func NewSpeech(options ...Option) (*Speech, error) {
-	return &Speech{client: New(options...)}, nil
+	client, err := NewClient(options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Speech{client: client}, nil
 }
 
 // Do synthesizes one request and returns the audio bytes.
@@ -51,9 +76,29 @@ func (c *Client) BytesSSML(ctx context.Context, ssml string, opts ...Option) ([]
 	return c.Do(ctx, SSML(ssml, opts...))
 }
 
+// DoWithBoundaries synthesizes one request and returns the audio bytes together
+// with the boundaries reported by the service, ordered by offset.
+func (c *Client) DoWithBoundaries(ctx context.Context, req Request) ([]byte, []Boundary, error) {
+	var (
+		buf        bytes.Buffer
+		boundaries []Boundary
+	)
+	_, err := c.writeRequest(ctx, req, &buf, func(b Boundary) {
+		boundaries = append(boundaries, b)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), boundaries, nil
+}
+
 // WriteRequestTo writes synthesized audio to w.
 func (c *Client) WriteRequestTo(ctx context.Context, req Request, w io.Writer) (int64, error) {
-	if strings.TrimSpace(req.Input) == "" {
+	return c.writeRequest(ctx, req, w, nil)
+}
+
+func (c *Client) writeRequest(ctx context.Context, req Request, w io.Writer, onBoundary func(Boundary)) (int64, error) {
+	if req.isEmpty() {
 		return 0, ErrEmptyInput
 	}
 
@@ -61,7 +106,12 @@ func (c *Client) WriteRequestTo(ctx context.Context, req Request, w io.Writer) (
 	if err != nil {
 		return 0, err
 	}
-	return comm.WriteStreamToContext(ctx, w)
+	if onBoundary == nil {
+		return comm.WriteStreamToContext(ctx, w)
+	}
+	return comm.WriteStreamWithBoundaries(ctx, w, func(b communicate.Boundary) {
+		onBoundary(boundaryFromInternal(b))
+	})
 }
 
 // WriteTo writes synthesized text audio to w.
@@ -84,14 +134,65 @@ func (c *Client) SaveSSML(ctx context.Context, ssml, path string, opts ...Option
 	return c.saveRequest(ctx, SSML(ssml, opts...), path)
 }
 
+// WriteWithSubtitles writes synthesized audio to audio and, once synthesis has
+// finished, subtitles built from the word boundaries to subtitles. The format
+// defaults to SRT.
+func (c *Client) WriteWithSubtitles(ctx context.Context, req Request, audio, subtitles io.Writer, opts ...SubtitleOption) (int64, error) {
+	var boundaries []Boundary
+	n, err := c.writeRequest(ctx, req, audio, func(b Boundary) {
+		boundaries = append(boundaries, b)
+	})
+	if err != nil {
+		return n, err
+	}
+	option := newSubtitleOption(opts...)
+	if err := WriteSubtitles(subtitles, BuildCues(boundaries, opts...), option.format); err != nil {
+		return n, fmt.Errorf("write subtitles: %w", err)
+	}
+	return n, nil
+}
+
+// SaveWithSubtitles writes synthesized audio to audioPath and matching subtitles to
+// subtitlesPath. Unless WithSubtitleFormat is given, the subtitle format is derived
+// from the extension of subtitlesPath.
+func (c *Client) SaveWithSubtitles(ctx context.Context, req Request, audioPath, subtitlesPath string, opts ...SubtitleOption) error {
+	var boundaries []Boundary
+	err := writeFileAtomically(audioPath, func(w io.Writer) error {
+		_, err := c.writeRequest(ctx, req, w, func(b Boundary) {
+			boundaries = append(boundaries, b)
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	option := newSubtitleOption(opts...)
+	format := option.format
+	if !option.formatSet {
+		format = subtitleFormatFromPath(subtitlesPath)
+	}
+	return writeFileAtomically(subtitlesPath, func(w io.Writer) error {
+		return WriteSubtitles(w, BuildCues(boundaries, opts...), format)
+	})
+}
+
 func (c *Client) saveRequest(ctx context.Context, req Request, path string) error {
+	return writeFileAtomically(path, func(w io.Writer) error {
+		_, err := c.WriteRequestTo(ctx, req, w)
+		return err
+	})
+}
+
+// writeFileAtomically writes to a temporary file and renames it to path on success.
+func writeFileAtomically(path string, write func(w io.Writer) error) error {
 	tmpPath := path + ".tmp"
 	f, err := os.Create(tmpPath)
 	if err != nil {
 		return fmt.Errorf("create file %s: %w", tmpPath, err)
 	}
 
-	if _, err := c.WriteRequestTo(ctx, req, f); err != nil {
+	if err := write(f); err != nil {
 		_ = f.Close()
 		_ = os.Remove(tmpPath)
 		return err
@@ -118,7 +219,7 @@ func (c *Client) StreamSSML(ctx context.Context, ssml string, opts ...Option) (i
 }
 
 func (c *Client) streamRequest(ctx context.Context, req Request) (io.ReadCloser, error) {
-	if strings.TrimSpace(req.Input) == "" {
+	if req.isEmpty() {
 		return nil, ErrEmptyInput
 	}
 
@@ -130,6 +231,32 @@ func (c *Client) streamRequest(ctx context.Context, req Request) (io.ReadCloser,
 	return pr, nil
 }
 
+// StreamWithBoundaries synthesizes one request and returns a streaming audio reader
+// together with a channel of boundaries. Boundaries are delivered while the audio is
+// produced, so the reader and the channel must be consumed concurrently. The channel
+// is closed when synthesis finishes; closing the reader stops synthesis early.
+func (c *Client) StreamWithBoundaries(ctx context.Context, req Request) (io.ReadCloser, <-chan Boundary, error) {
+	if req.isEmpty() {
+		return nil, nil, ErrEmptyInput
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	boundaries := make(chan Boundary, boundaryBufferSize)
+	go func() {
+		defer cancel()
+		defer close(boundaries)
+		_, err := c.writeRequest(ctx, req, pw, func(b Boundary) {
+			select {
+			case boundaries <- b:
+			case <-ctx.Done():
+			}
+		})
+		_ = pw.CloseWithError(err)
+	}()
+	return &cancelReadCloser{ReadCloser: pr, cancel: cancel}, boundaries, nil
+}
+
 // Batch synthesizes all items and returns structured per-item results.
 func (c *Client) Batch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
 	if len(items) == 0 {
@@ -204,9 +331,9 @@ func (c *Client) WriteZIP(ctx context.Context, w io.Writer, items []BatchItem, m
 	return nil
 }
 
-// Voices lists available voices.
+// Voices lists available voices. They are cached by the VoiceManager of the client.
 func (c *Client) Voices(ctx context.Context) ([]Voice, error) {
-	return c.vm.ListVoicesContext(ctx)
+	return c.vm.Voices(ctx)
 }
 
 // FindVoice finds the first matching voice.
@@ -222,14 +349,24 @@ func (c *Client) FindVoice(ctx context.Context, filter VoiceFilter) (Voice, erro
 }
 
 func (c *Client) newCommunicate(req Request) (*communicate.Communicate, error) {
-	merged := c.mergeOptions(req.Options...).toInternalOption()
+	return newCommunicate(req, c.mergeOptions(req.Options...).toInternalOption())
+}
+
+func newCommunicate(req Request, opt *communicateOption.CommunicateOption) (*communicate.Communicate, error) {
 	switch req.Type {
 	case InputText:
-		return communicate.NewCommunicate(communicate.InputText, req.Input, merged)
+		if len(req.Spans) > 0 {
+			spans := make([]communicate.Span, len(req.Spans))
+			for i, span := range req.Spans {
+				spans[i] = communicate.Span{Lang: span.Lang, Text: span.Text}
+			}
+			return communicate.NewCommunicateSpans(spans, opt)
+		}
+		return communicate.NewCommunicate(communicate.InputText, req.Input, opt)
 	case InputSSML:
-		return communicate.NewCommunicate(communicate.InputSSML, req.Input, merged)
+		return communicate.NewCommunicate(communicate.InputSSML, req.Input, opt)
 	default:
-		return communicate.NewCommunicate(communicate.InputText, req.Input, merged)
+		return communicate.NewCommunicate(communicate.InputText, req.Input, opt)
 	}
 }
 
@@ -274,6 +411,20 @@ func FilterVoices(voices []Voice, filter VoiceFilter) []Voice {
 	return result
 }
 
+// boundaryBufferSize lets boundaries run slightly ahead of a reader that is busy with audio.
+const boundaryBufferSize = 64
+
+// cancelReadCloser cancels the producing context when the reader is closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReadCloser) Close() error {
+	r.cancel()
+	return r.ReadCloser.Close()
+}
+
 func writeJSON(w io.Writer, value any) error {
 	return json.NewEncoder(w).Encode(value)
 }
@@ -23,6 +23,9 @@ func main() {
 		pitch     = flag.String("pitch", "", "speech pitch, e.g. +5Hz")
 		volume    = flag.String("volume", "", "speech volume, e.g. +10%")
 		stream    = flag.Bool("stream", false, "use Stream/StreamSSML instead of Save/SaveSSML")
+		lexicon   = flag.String("lexicon", "", "pronunciation lexicon file, CSV or W3C PLS")
+		dryRun    = flag.Bool("dry-run", false, "print the SSML and lexicon matches instead of synthesizing")
+		proxyURL  = flag.String("proxy", "", "proxy URL, e.g. socks5h://127.0.0.1:1080; if empty, use HTTP(S)_PROXY")
 	)
 	flag.Parse()
 
@@ -40,10 +43,37 @@ func main() {
 		opts = append(opts, edgetts.WithVolume(*volume))
 	}
 
-	client := edgetts.New(opts...)
+	if *lexicon != "" {
+		lex, err := edgetts.LoadLexicon(*lexicon)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, edgetts.WithLexicon(lex))
+	}
+
+	if *proxyURL != "" {
+		opts = append(opts, edgetts.WithProxyURL(*proxyURL))
+	} else {
+		opts = append(opts, edgetts.WithProxyFromEnvironment())
+	}
+
+	client, err := edgetts.NewClient(opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
 	ctx := context.Background()
 
 	isSSML := strings.EqualFold(*inputType, "ssml")
+	if *dryRun {
+		req := edgetts.Text(*text)
+		if isSSML {
+			req = edgetts.SSML(*text)
+		}
+		if err := printDryRun(client, req); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if *output == "" {
 		var (
 			data []byte
@@ -67,6 +97,24 @@ func main() {
 	fmt.Printf("saved audio to %s\n", *output)
 }
 
+func printDryRun(client *edgetts.Client, req edgetts.Request) error {
+	result, err := client.DryRun(req)
+	if err != nil {
+		return err
+	}
+	for i, ssml := range result.SSML {
+		fmt.Printf("turn %d:\n%s\n", i+1, ssml)
+	}
+	for _, match := range result.LexiconMatches {
+		if match.Entry.Alias != "" {
+			fmt.Printf("lexicon: %q -> alias %q\n", match.Text, match.Entry.Alias)
+		} else {
+			fmt.Printf("lexicon: %q -> phoneme %q (%s)\n", match.Text, match.Entry.Phoneme, match.Entry.Alphabet)
+		}
+	}
+	return nil
+}
+
 func saveToFile(ctx context.Context, client *edgetts.Client, outputPath, input string, isSSML, stream bool) error {
 	if !stream {
 		if isSSML {
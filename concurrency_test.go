@@ -0,0 +1,153 @@
+package edgetts_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts"
+	"github.com/lib-x/edgetts/edgettstest"
+	"github.com/lib-x/edgetts/ssml"
+)
+
+// longText returns words long words, so that a few thousand of them span several chunks.
+func longText(words int) string {
+	padding := strings.Repeat("x", 60)
+	parts := make([]string, words)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("w%d%s", i, padding)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestChunkConcurrencyKeepsOrder(t *testing.T) {
+	// the first chunk is the slowest, so later chunks finish before it
+	server := edgettstest.NewServer(edgettstest.WithAudio(func(text string) []byte {
+		if strings.HasPrefix(text, "w0x") {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return []byte(text + "|")
+	}))
+	defer server.Close()
+
+	input := longText(5000)
+	sequential := edgetts.New(server.Option())
+	wantAudio, wantBoundaries, err := sequential.DoWithBoundaries(context.Background(), edgetts.Text(input))
+	if err != nil {
+		t.Fatalf("sequential DoWithBoundaries() error = %v", err)
+	}
+	chunks := bytes.Count(wantAudio, []byte("|"))
+	if chunks < 4 {
+		t.Fatalf("input produced %d chunks, want at least 4", chunks)
+	}
+
+	dials := server.Dials()
+	concurrent := edgetts.New(server.Option(), edgetts.WithChunkConcurrency(3))
+	gotAudio, gotBoundaries, err := concurrent.DoWithBoundaries(context.Background(), edgetts.Text(input))
+	if err != nil {
+		t.Fatalf("concurrent DoWithBoundaries() error = %v", err)
+	}
+	if !bytes.Equal(gotAudio, wantAudio) {
+		t.Fatal("concurrent audio differs from sequential audio")
+	}
+	if !reflect.DeepEqual(gotBoundaries, wantBoundaries) {
+		t.Fatal("concurrent boundaries differ from sequential boundaries")
+	}
+	if got := server.Dials() - dials; got != 3 {
+		t.Fatalf("concurrent dials = %d, want 3", got)
+	}
+}
+
+func TestChunkConcurrencyReportsFailure(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{MalformedMetadata: true})
+
+	client := edgetts.New(server.Option(), edgetts.WithChunkConcurrency(2), edgetts.WithPrefetchWindow(4))
+	_, err := client.Bytes(context.Background(), longText(5000))
+	if err == nil {
+		t.Fatal("Bytes() error = nil, want the injected protocol error")
+	}
+}
+
+func TestWithMaxChunkBytes(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	input := "First sentence here. Second sentence here. Third sentence here."
+	client := edgetts.New(server.Option(), edgetts.WithMaxChunkBytes(30))
+	audio, err := client.Bytes(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	if string(audio) != strings.ReplaceAll(input, ". ", ".") {
+		t.Fatalf("audio = %q", audio)
+	}
+
+	var texts []string
+	for _, turn := range server.Turns() {
+		texts = append(texts, turn.Text)
+	}
+	want := []string{"First sentence here.", "Second sentence here.", "Third sentence here."}
+	if !reflect.DeepEqual(texts, want) {
+		t.Fatalf("turns = %q, want %q", texts, want)
+	}
+}
+
+func TestLargeSSMLIsSplitIntoTurns(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	doc := ssml.NewSpeak("en-US", ssml.NewVoice("en-US-GuyNeural",
+		ssml.NewProsody(
+			ssml.NewSentence(ssml.Text("First sentence here.")),
+			ssml.NewSentence(ssml.Text("Second sentence here.")),
+			ssml.NewSentence(ssml.Text("Third sentence here.")),
+		).WithRate("+10%"),
+	))
+	client := edgetts.New(server.Option(), edgetts.WithMaxChunkBytes(240))
+	if _, err := client.BytesSSML(context.Background(), doc.String()); err != nil {
+		t.Fatalf("BytesSSML() error = %v", err)
+	}
+
+	var texts []string
+	for _, turn := range server.Turns() {
+		if !strings.Contains(turn.SSML, `<voice name="en-US-GuyNeural"><prosody rate="+10%">`) {
+			t.Fatalf("turn SSML = %s, want the voice and prosody context", turn.SSML)
+		}
+		texts = append(texts, turn.Text)
+	}
+	want := []string{"First sentence here.", "Second sentence here.", "Third sentence here."}
+	if !reflect.DeepEqual(texts, want) {
+		t.Fatalf("turns = %q, want %q", texts, want)
+	}
+}
+
+func TestRIFFKeepsFirstHeader(t *testing.T) {
+	header := append([]byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00"), make([]byte, 16)...)
+	header = append(header, "data\x00\x00\x00\x00"...)
+	server := edgettstest.NewServer(edgettstest.WithAudio(func(text string) []byte {
+		return append(append([]byte(nil), header...), text+"|"...)
+	}))
+	defer server.Close()
+
+	input := longText(100)
+	for _, concurrency := range []int{1, 3} {
+		client := edgetts.New(server.Option(), edgetts.WithOutputFormat(edgetts.OutputRIFFPCM24kHz),
+			edgetts.WithMaxChunkBytes(1024), edgetts.WithChunkConcurrency(concurrency))
+		audio, err := client.Bytes(context.Background(), input)
+		if err != nil {
+			t.Fatalf("concurrency %d: Bytes() error = %v", concurrency, err)
+		}
+		if chunks := bytes.Count(audio, []byte("|")); chunks < 2 {
+			t.Fatalf("concurrency %d: input produced %d chunks, want several", concurrency, chunks)
+		}
+		if !bytes.HasPrefix(audio, header) || bytes.Count(audio, []byte("RIFF")) != 1 {
+			t.Fatalf("concurrency %d: audio has %d RIFF headers, want 1 at the start", concurrency, bytes.Count(audio, []byte("RIFF")))
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package edgetts
+
+// DryRunResult describes how a request would be synthesized.
+type DryRunResult struct {
+	// SSML holds the document sent for every turn, in order.
+	SSML []string
+	// LexiconMatches lists the words rewritten by WithLexicon, in the order found.
+	LexiconMatches []LexiconMatch
+}
+
+// DryRun prepares req exactly as it would be synthesized, running normalizers and
+// lexicons and splitting long texts into turns, but does not connect to the service.
+// Use it to check the generated SSML and which lexicon entries apply.
+func (c *Client) DryRun(req Request) (DryRunResult, error) {
+	if req.isEmpty() {
+		return DryRunResult{}, ErrEmptyInput
+	}
+
+	var result DryRunResult
+	merged := c.mergeOptions(req.Options...)
+	opt := merged.toInternalOption()
+	opt.Normalizers = merged.normalizers(func(match LexiconMatch) {
+		result.LexiconMatches = append(result.LexiconMatches, match)
+	})
+	comm, err := newCommunicate(req, opt)
+	if err != nil {
+		return DryRunResult{}, err
+	}
+	result.SSML = comm.SSML()
+	return result, nil
+}
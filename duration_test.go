@@ -0,0 +1,47 @@
+package edgetts_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts"
+	"github.com/lib-x/edgetts/edgettstest"
+)
+
+func TestBoundariesShiftedByAudioDuration(t *testing.T) {
+	server := edgettstest.NewServer(edgettstest.WithAudio(edgettstest.MP3Audio))
+	defer server.Close()
+
+	// one-letter words keep the text long enough for two chunks with little audio
+	input := strings.Repeat("a. ", 24000)
+	var (
+		firstChunkAudio int
+		nextChunkStart  time.Duration = -1
+	)
+	for event, err := range edgetts.New(server.Option()).EventSeq(context.Background(), edgetts.Text(input)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch e := event.(type) {
+		case *edgetts.AudioChunk:
+			if e.Index == 0 {
+				firstChunkAudio += len(e.Data)
+			}
+		case *edgetts.WordBoundary:
+			if e.Index == 1 && nextChunkStart < 0 {
+				nextChunkStart = e.Offset
+			}
+		}
+	}
+	if nextChunkStart < 0 {
+		t.Fatal("input was not split into two chunks")
+	}
+
+	// 144-byte frames of 24ms each, then the 100ms lead of the fake before the first word
+	want := time.Duration(firstChunkAudio/144)*24*time.Millisecond + 100*time.Millisecond
+	if nextChunkStart != want {
+		t.Fatalf("first boundary of the second chunk at %v, want %v", nextChunkStart, want)
+	}
+}
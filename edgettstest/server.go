@@ -0,0 +1,514 @@
+// Package edgettstest provides a local fake of the Edge read-aloud service for tests.
+//
+// The server speaks the same websocket protocol as the real endpoint: it accepts
+// speech.config and ssml messages and answers every turn with turn.start,
+// audio.metadata word (and optionally sentence) boundaries, binary audio frames and
+// turn.end. It also serves a voice list. Failures can be injected per handshake or
+// per turn to exercise error handling and retries.
+package edgettstest
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/lib-x/edgetts"
+)
+
+const (
+	synthesizePath = "/consumer/speech/synthesize/readaloud/edge/v1"
+	voiceListPath  = "/consumer/speech/synthesize/readaloud/voices/list"
+
+	// wordLead is the offset of the first word, as the real service leaves a short
+	// silence before speaking.
+	wordLead = 100 * time.Millisecond
+	// wordGap is the pause between two words.
+	wordGap = 50 * time.Millisecond
+	// runeDuration is how long one character takes to speak.
+	runeDuration = 60 * time.Millisecond
+)
+
+// DefaultVoices is the voice list served unless WithVoices is used.
+var DefaultVoices = []edgetts.Voice{
+	{
+		Name:           "Microsoft Server Speech Text to Speech Voice (en-US, GuyNeural)",
+		ShortName:      "en-US-GuyNeural",
+		Gender:         "Male",
+		Locale:         "en-US",
+		SuggestedCodec: "audio-24khz-48kbitrate-mono-mp3",
+		FriendlyName:   "Microsoft Guy Online (Natural) - English (United States)",
+		Status:         "GA",
+	},
+	{
+		Name:           "Microsoft Server Speech Text to Speech Voice (zh-CN, XiaoxiaoNeural)",
+		ShortName:      "zh-CN-XiaoxiaoNeural",
+		Gender:         "Female",
+		Locale:         "zh-CN",
+		SuggestedCodec: "audio-24khz-48kbitrate-mono-mp3",
+		FriendlyName:   "Microsoft Xiaoxiao Online (Natural) - Chinese (Mainland)",
+		Status:         "GA",
+	},
+}
+
+// mp3Frame is one frame of silent MPEG-2 layer III audio at 24kHz and 48kbps, the
+// default output format of the service. It lasts mp3FrameDuration.
+var mp3Frame = append([]byte{0xFF, 0xF3, 0x64, 0xC0}, make([]byte, 140)...)
+
+const mp3FrameDuration = 24 * time.Millisecond
+
+// MP3Audio is an audio function for WithAudio producing silent MP3 frames that last as
+// long as the boundaries reported for text, followed by a short trailing pause.
+func MP3Audio(text string) []byte {
+	end := wordLead
+	for _, sentence := range splitSentences(text) {
+		for _, word := range strings.Fields(sentence) {
+			end += time.Duration(utf8.RuneCountInString(word))*runeDuration + wordGap
+		}
+	}
+	end += wordLead
+	frames := int((end + mp3FrameDuration - 1) / mp3FrameDuration)
+	return bytes.Repeat(mp3Frame, frames)
+}
+
+// Fault describes a failure injected into one turn.
+type Fault struct {
+	// Delay is waited before every message of the turn.
+	Delay time.Duration
+	// CloseMidStream closes the connection after CloseAfterFrames audio frames.
+	CloseMidStream   bool
+	CloseAfterFrames int
+	// OmitTurnEnd leaves the turn open: no turn.end is sent and the connection stays idle.
+	OmitTurnEnd bool
+	// MalformedMetadata sends an audio.metadata message whose body is not valid JSON.
+	MalformedMetadata bool
+}
+
+// Turn is one synthesis request received by the server.
+type Turn struct {
+	ConnectionID string
+	RequestID    string
+	// Query and Header are those of the websocket handshake that carried the turn.
+	Query  url.Values
+	Header http.Header
+	// SpeechConfig is the body of the last speech.config message on the connection.
+	SpeechConfig string
+	SSML         string
+	// Text is the character data of SSML, which is what the fake speaks.
+	Text string
+}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithVoices replaces the served voice list.
+func WithVoices(voices []edgetts.Voice) Option {
+	return func(s *Server) {
+		s.voices = voices
+	}
+}
+
+// WithAudio sets how the audio of a turn is produced from its text. By default the
+// audio is the UTF-8 text itself, which makes the output easy to assert on.
+func WithAudio(audio func(text string) []byte) Option {
+	return func(s *Server) {
+		s.audio = audio
+	}
+}
+
+// WithFrameSize splits the audio of a turn into binary frames of at most n bytes.
+// The default sends one frame per turn.
+func WithFrameSize(n int) Option {
+	return func(s *Server) {
+		s.frameSize = n
+	}
+}
+
+// Server is a running fake service. Create one with NewServer and Close it when done.
+type Server struct {
+	// URL is the base http URL of the server.
+	URL string
+
+	server    *httptest.Server
+	upgrader  websocket.Upgrader
+	voices    []edgetts.Voice
+	audio     func(text string) []byte
+	frameSize int
+
+	mu              sync.Mutex
+	handshakeStatus int
+	handshakeFaults int
+	faults          []Fault
+	turns           []Turn
+	dials           int
+	conns           map[*websocket.Conn]struct{}
+}
+
+// NewServer starts a fake service listening on a local port.
+func NewServer(opts ...Option) *Server {
+	return newServer(httptest.NewServer, opts)
+}
+
+// NewTLSServer starts a fake service serving https and wss with a self-signed
+// certificate, which clients must trust through their TLS configuration.
+func NewTLSServer(opts ...Option) *Server {
+	return newServer(httptest.NewTLSServer, opts)
+}
+
+func newServer(start func(http.Handler) *httptest.Server, opts []Option) *Server {
+	s := &Server{
+		voices:   DefaultVoices,
+		audio:    func(text string) []byte { return []byte(text) },
+		upgrader: websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+		conns:    make(map[*websocket.Conn]struct{}),
+	}
+	for _, apply := range opts {
+		apply(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(synthesizePath, s.serveSynthesis)
+	mux.HandleFunc(voiceListPath, s.serveVoiceList)
+	s.server = start(mux)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close closes all connections and shuts the server down.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.server.Close()
+}
+
+// Certificate returns the certificate of a server started with NewTLSServer, or nil.
+func (s *Server) Certificate() *x509.Certificate {
+	return s.server.Certificate()
+}
+
+// WebSocketURL returns the synthesis endpoint of the server.
+func (s *Server) WebSocketURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + synthesizePath
+}
+
+// VoiceListURL returns the voice list endpoint of the server.
+func (s *Server) VoiceListURL() string {
+	return s.URL + voiceListPath
+}
+
+// Option returns a client option that sends synthesis and voice listing to this server.
+func (s *Server) Option() edgetts.Option {
+	return join(edgetts.WithEndpoint(s.WebSocketURL()), edgetts.WithVoiceListEndpoint(s.VoiceListURL()))
+}
+
+// join combines client options into one. The option argument type is unexported, so
+// it is inferred rather than named.
+func join[O ~func(T), T any](opts ...O) O {
+	return func(option T) {
+		for _, apply := range opts {
+			apply(option)
+		}
+	}
+}
+
+// Transport returns a transport that dials this server whatever endpoint the client
+// asks for, keeping the query so that tokens and connection IDs are still visible.
+// It is useful together with a custom Transport wrapper.
+func (s *Server) Transport() edgetts.Transport {
+	return &redirectTransport{target: s.WebSocketURL()}
+}
+
+// RejectHandshakes makes the next n websocket upgrades fail with status. Rejections
+// carry a Date header like the real service.
+func (s *Server) RejectHandshakes(status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handshakeStatus = status
+	s.handshakeFaults = n
+}
+
+// InjectFaults queues faults that are applied, in order, to the next turns.
+func (s *Server) InjectFaults(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// Turns returns the synthesis requests received so far.
+func (s *Server) Turns() []Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Turn(nil), s.turns...)
+}
+
+// Dials returns how many websocket connections were accepted.
+func (s *Server) Dials() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dials
+}
+
+func (s *Server) serveVoiceList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.voices)
+}
+
+func (s *Server) serveSynthesis(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.handshakeFaults > 0 {
+		s.handshakeFaults--
+		status := s.handshakeStatus
+		s.mu.Unlock()
+		w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	s.mu.Unlock()
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.dials++
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	connectionID := r.URL.Query().Get("ConnectionId")
+	var speechConfig string
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		headers, body := splitMessage(message)
+		switch headers["Path"] {
+		case "speech.config":
+			speechConfig = body
+		case "ssml":
+			turn := Turn{
+				ConnectionID: connectionID,
+				RequestID:    headers["X-RequestId"],
+				Query:        r.URL.Query(),
+				Header:       r.Header.Clone(),
+				SpeechConfig: speechConfig,
+				SSML:         body,
+				Text:         speakableText(body),
+			}
+			if !s.respond(conn, turn) {
+				return
+			}
+		}
+	}
+}
+
+// respond plays one turn and reports whether the connection should stay open.
+func (s *Server) respond(conn *websocket.Conn, turn Turn) bool {
+	s.mu.Lock()
+	s.turns = append(s.turns, turn)
+	var fault Fault
+	if len(s.faults) > 0 {
+		fault = s.faults[0]
+		s.faults = s.faults[1:]
+	}
+	s.mu.Unlock()
+
+	send := func(messageType int, data []byte) bool {
+		if fault.Delay > 0 {
+			time.Sleep(fault.Delay)
+		}
+		return conn.WriteMessage(messageType, data) == nil
+	}
+	text := func(path, body string) bool {
+		return send(websocket.TextMessage, []byte(fmt.Sprintf(
+			"X-RequestId:%s\r\nContent-Type:application/json; charset=utf-8\r\nPath:%s\r\n\r\n%s", turn.RequestID, path, body)))
+	}
+
+	if !text("turn.start", `{"context":{"serviceTag":"edgettstest"}}`) {
+		return false
+	}
+	if fault.MalformedMetadata {
+		return text("audio.metadata", `{"Metadata":[`)
+	}
+	sentences := strings.Contains(turn.SpeechConfig, `"sentenceBoundaryEnabled":true`)
+	for _, metadata := range boundaries(turn.Text, sentences) {
+		if !text("audio.metadata", metadata) {
+			return false
+		}
+	}
+
+	audio := s.audio(turn.Text)
+	frames := 0
+	for len(audio) > 0 {
+		if fault.CloseMidStream && frames == fault.CloseAfterFrames {
+			return false
+		}
+		n := len(audio)
+		if s.frameSize > 0 && n > s.frameSize {
+			n = s.frameSize
+		}
+		header := fmt.Sprintf("X-RequestId:%s\r\nContent-Type:audio/mpeg\r\nPath:audio\r\n", turn.RequestID)
+		frame := binary.BigEndian.AppendUint16(nil, uint16(len(header)))
+		frame = append(append(frame, header...), audio[:n]...)
+		if !send(websocket.BinaryMessage, frame) {
+			return false
+		}
+		audio = audio[n:]
+		frames++
+	}
+	if fault.CloseMidStream {
+		return false
+	}
+	if fault.OmitTurnEnd {
+		return true
+	}
+	return text("turn.end", "{}")
+}
+
+type metadataText struct {
+	Text         string `json:"Text"`
+	Length       int    `json:"Length"`
+	BoundaryType string `json:"BoundaryType"`
+}
+
+type metadataData struct {
+	Offset   int64        `json:"Offset"`
+	Duration int64        `json:"Duration"`
+	Text     metadataText `json:"text"`
+}
+
+type metadataEntry struct {
+	Type string       `json:"Type"`
+	Data metadataData `json:"Data"`
+}
+
+// boundaries returns one audio.metadata body per boundary of text. Offsets are
+// expressed in 100ns ticks and computed from the number of characters spoken.
+func boundaries(text string, sentences bool) []string {
+	var bodies []string
+	offset := wordLead
+	for _, sentence := range splitSentences(text) {
+		words := strings.Fields(sentence)
+		if len(words) == 0 {
+			continue
+		}
+		var wordBodies []string
+		start := offset
+		for _, word := range words {
+			duration := time.Duration(utf8.RuneCountInString(word)) * runeDuration
+			wordBodies = append(wordBodies, metadata("WordBoundary", offset, duration, word))
+			offset += duration + wordGap
+		}
+		if sentences {
+			bodies = append(bodies, metadata("SentenceBoundary", start, offset-wordGap-start, strings.TrimSpace(sentence)))
+		}
+		bodies = append(bodies, wordBodies...)
+	}
+	return bodies
+}
+
+func metadata(kind string, offset, duration time.Duration, text string) string {
+	data, _ := json.Marshal(map[string][]metadataEntry{"Metadata": {{
+		Type: kind,
+		Data: metadataData{
+			Offset:   int64(offset / 100),
+			Duration: int64(duration / 100),
+			Text:     metadataText{Text: text, Length: utf8.RuneCountInString(text), BoundaryType: kind},
+		},
+	}}})
+	return string(data)
+}
+
+// splitSentences splits text after sentence-ending punctuation.
+func splitSentences(text string) []string {
+	var (
+		sentences []string
+		current   strings.Builder
+	)
+	for _, r := range text {
+		current.WriteRune(r)
+		if strings.ContainsRune(".!?。！？", r) {
+			sentences = append(sentences, current.String())
+			current.Reset()
+		}
+	}
+	if strings.TrimFunc(current.String(), unicode.IsSpace) != "" {
+		sentences = append(sentences, current.String())
+	}
+	return sentences
+}
+
+// speakableText returns the character data of an SSML document.
+func speakableText(ssml string) string {
+	decoder := xml.NewDecoder(strings.NewReader(ssml))
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ssml
+		}
+		if data, ok := token.(xml.CharData); ok {
+			text.Write(data)
+		}
+	}
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+func splitMessage(message []byte) (map[string]string, string) {
+	headers := make(map[string]string)
+	head, body, _ := strings.Cut(string(message), "\r\n\r\n")
+	for _, line := range strings.Split(head, "\r\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return headers, body
+}
+
+// redirectTransport dials target instead of the requested endpoint.
+type redirectTransport struct {
+	target string
+}
+
+func (t *redirectTransport) Dial(ctx context.Context, rawURL string, header http.Header) (edgetts.Conn, *http.Response, error) {
+	requested, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := url.Parse(t.target)
+	if err != nil {
+		return nil, nil, err
+	}
+	target.RawQuery = requested.RawQuery
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, target.String(), header)
+	if err != nil {
+		return nil, resp, err
+	}
+	return conn, resp, nil
+}
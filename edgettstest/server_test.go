@@ -0,0 +1,294 @@
+package edgettstest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts"
+	"github.com/lib-x/edgetts/edgettstest"
+)
+
+func TestServerSynthesizes(t *testing.T) {
+	server := edgettstest.NewServer(edgettstest.WithFrameSize(4))
+	defer server.Close()
+
+	client := edgetts.New(server.Option(), edgetts.WithVoice("en-US-GuyNeural"))
+	audio, boundaries, err := client.DoWithBoundaries(context.Background(), edgetts.Request{Input: "hello fake world"})
+	if err != nil {
+		t.Fatalf("DoWithBoundaries() error = %v", err)
+	}
+	if string(audio) != "hello fake world" {
+		t.Fatalf("audio = %q, want the spoken text", audio)
+	}
+	var words []string
+	for _, b := range boundaries {
+		words = append(words, b.Text)
+	}
+	if got := strings.Join(words, " "); got != "hello fake world" {
+		t.Fatalf("boundaries = %q", got)
+	}
+
+	turns := server.Turns()
+	if len(turns) != 1 {
+		t.Fatalf("turns = %d, want 1", len(turns))
+	}
+	if !strings.Contains(turns[0].SSML, "en-US-GuyNeural") || turns[0].ConnectionID == "" || turns[0].SpeechConfig == "" {
+		t.Fatalf("turn = %+v", turns[0])
+	}
+}
+
+func TestServerSentenceBoundaries(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	client := edgetts.New(server.Option(), edgetts.WithSentenceBoundaries())
+	_, boundaries, err := client.DoWithBoundaries(context.Background(), edgetts.Request{Input: "One two. Three four."})
+	if err != nil {
+		t.Fatalf("DoWithBoundaries() error = %v", err)
+	}
+	var sentences []string
+	for _, b := range boundaries {
+		if b.Kind == edgetts.BoundarySentence {
+			sentences = append(sentences, b.Text)
+		}
+	}
+	if len(sentences) != 2 || sentences[0] != "One two." || sentences[1] != "Three four." {
+		t.Fatalf("sentences = %q", sentences)
+	}
+}
+
+func TestServerEvents(t *testing.T) {
+	server := edgettstest.NewServer(edgettstest.WithFrameSize(4))
+	defer server.Close()
+
+	client := edgetts.New(server.Option())
+	stream, err := client.Events(context.Background(), edgetts.Text("hello event stream"))
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	var kinds []string
+	for event := range stream.C {
+		var kind string
+		switch event.(type) {
+		case *edgetts.TurnStart:
+			kind = "start"
+		case *edgetts.AudioChunk:
+			kind = "audio"
+		case *edgetts.WordBoundary:
+			kind = "word"
+		case *edgetts.TurnEnd:
+			kind = "end"
+		default:
+			t.Fatalf("unexpected event %#v", event)
+		}
+		// record runs of one kind, since the fake splits audio into several frames
+		if n := len(kinds); n == 0 || kinds[n-1] != kind {
+			kinds = append(kinds, kind)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+	// the fake sends every boundary of a turn before its audio
+	if got := strings.Join(kinds, " "); got != "start word audio end" {
+		t.Fatalf("events = %s, want start word audio end", got)
+	}
+}
+
+func TestServerRejectsHandshake(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	// after a 403 whose Date header shows a skewed local clock, the client resyncs and
+	// dials once more; the second rejection covers that retry, should it happen
+	server.RejectHandshakes(http.StatusForbidden, 2)
+
+	client := edgetts.New(server.Option())
+	_, err := client.Bytes(context.Background(), "hello")
+	var handshakeErr *edgetts.HandshakeError
+	if !errors.As(err, &handshakeErr) || handshakeErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("Bytes() error = %v, want 403 HandshakeError", err)
+	}
+	if handshakeErr.ConnectionID == "" {
+		t.Fatalf("HandshakeError = %+v, want a connection ID", handshakeErr)
+	}
+	if server.Dials() != 0 {
+		t.Fatalf("dials = %d, want every handshake rejected", server.Dials())
+	}
+}
+
+func TestServerMidStreamCloseIsRetried(t *testing.T) {
+	server := edgettstest.NewServer(edgettstest.WithFrameSize(2))
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{CloseMidStream: true, CloseAfterFrames: 1})
+
+	client := edgetts.New(server.Option(), edgetts.WithRetry(2, time.Millisecond))
+	audio, err := client.Bytes(context.Background(), "resume")
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	if string(audio) != "resume" {
+		t.Fatalf("audio = %q, want %q", audio, "resume")
+	}
+	if server.Dials() != 2 {
+		t.Fatalf("dials = %d, want 2", server.Dials())
+	}
+}
+
+func TestServerRetryWithDifferentAudio(t *testing.T) {
+	retries := map[string]func(attempt int32, text string) string{
+		"different": func(attempt int32, text string) string { return fmt.Sprintf("%d:%s", attempt, text) },
+		"shorter": func(attempt int32, text string) string {
+			if attempt > 1 {
+				return text[:1]
+			}
+			return text
+		},
+	}
+	for name, audio := range retries {
+		var attempts atomic.Int32
+		server := edgettstest.NewServer(edgettstest.WithFrameSize(2), edgettstest.WithAudio(func(text string) []byte {
+			return []byte(audio(attempts.Add(1), text))
+		}))
+		server.InjectFaults(edgettstest.Fault{CloseMidStream: true, CloseAfterFrames: 1})
+
+		client := edgetts.New(server.Option(), edgetts.WithRetry(2, time.Millisecond))
+		got, err := client.Bytes(context.Background(), "resume")
+		server.Close()
+		var resumeErr *edgetts.ResumeError
+		if !errors.As(err, &resumeErr) || resumeErr.Delivered != 2 {
+			t.Fatalf("%s: Bytes() = %q, %v, want ResumeError after 2 bytes", name, got, err)
+		}
+	}
+}
+
+func TestServerMidStreamCloseWithoutRetry(t *testing.T) {
+	server := edgettstest.NewServer(edgettstest.WithFrameSize(2))
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{CloseMidStream: true, CloseAfterFrames: 1})
+
+	client := edgetts.New(server.Option())
+	audio, err := client.Bytes(context.Background(), "truncated")
+	var closeErr *edgetts.CloseError
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("Bytes() = %q, %v, want CloseError", audio, err)
+	}
+	turn := server.Turns()[0]
+	if closeErr.ConnectionID != turn.ConnectionID || closeErr.RequestID != turn.RequestID {
+		t.Fatalf("CloseError = %+v, want the IDs of turn %+v", closeErr, turn)
+	}
+}
+
+func TestServerReusesConnectionAcrossChunks(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	client := edgetts.New(server.Option(), edgetts.WithMaxChunkBytes(16))
+	input := "one two three four five six seven eight nine ten"
+	audio, err := client.Bytes(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	// the fake server speaks each chunk without the whitespace it was split at
+	if strings.ReplaceAll(string(audio), " ", "") != strings.ReplaceAll(input, " ", "") {
+		t.Fatalf("audio = %q, want the spoken text", audio)
+	}
+	if turns := len(server.Turns()); turns < 2 {
+		t.Fatalf("turns = %d, want several chunks", turns)
+	}
+	if server.Dials() != 1 {
+		t.Fatalf("dials = %d, want 1", server.Dials())
+	}
+}
+
+func TestServerMalformedMetadata(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{MalformedMetadata: true})
+
+	client := edgetts.New(server.Option())
+	_, err := client.Bytes(context.Background(), "hello")
+	var protocolErr *edgetts.ProtocolError
+	if !errors.As(err, &protocolErr) {
+		t.Fatalf("Bytes() error = %v, want ProtocolError", err)
+	}
+	turn := server.Turns()[0]
+	if protocolErr.ConnectionID != turn.ConnectionID || protocolErr.RequestID != turn.RequestID {
+		t.Fatalf("ProtocolError = %+v, want the IDs of turn %+v", protocolErr, turn)
+	}
+}
+
+func TestServerDelay(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{Delay: 20 * time.Millisecond})
+
+	client := edgetts.New(server.Option())
+	start := time.Now()
+	if _, err := client.Bytes(context.Background(), "slow"); err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Fatalf("elapsed = %v, want the delay applied to every message", elapsed)
+	}
+}
+
+func TestServerVoiceList(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	resp, err := http.Get(server.VoiceListURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var voices []edgetts.Voice
+	if err := json.NewDecoder(resp.Body).Decode(&voices); err != nil {
+		t.Fatal(err)
+	}
+	if len(voices) != len(edgettstest.DefaultVoices) || voices[0].ShortName != edgettstest.DefaultVoices[0].ShortName {
+		t.Fatalf("voices = %+v", voices)
+	}
+}
+
+func TestServerClientVoices(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	voice, err := edgetts.New(server.Option()).FindVoice(context.Background(), edgetts.VoiceFilter{Locale: "zh-CN"})
+	if err != nil {
+		t.Fatalf("FindVoice() error = %v", err)
+	}
+	if voice.ShortName != "zh-CN-XiaoxiaoNeural" {
+		t.Fatalf("voice = %+v", voice)
+	}
+}
+
+func TestServerSeesConfiguredIdentity(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	client := edgetts.New(server.Option(),
+		edgetts.WithTrustedClientToken("custom-token"),
+		edgetts.WithBrowserVersion("140.0.3485.14"),
+	)
+	if _, err := client.Bytes(context.Background(), "hello"); err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	turn := server.Turns()[0]
+	if got := turn.Query.Get("TrustedClientToken"); got != "custom-token" {
+		t.Fatalf("TrustedClientToken = %q", got)
+	}
+	if got := turn.Query.Get("Sec-MS-GEC-Version"); got != "1-140.0.3485.14" {
+		t.Fatalf("Sec-MS-GEC-Version = %q", got)
+	}
+	if ua := turn.Header.Get("User-Agent"); !strings.Contains(ua, "Edg/140.0.0.0") {
+		t.Fatalf("User-Agent = %q", ua)
+	}
+}
@@ -1,10 +1,73 @@
 package edgetts
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib-x/edgetts/internal/communicate"
+	"github.com/lib-x/edgetts/internal/validate"
+)
 
 var (
 	ErrEmptyInput      = errors.New("empty input")
 	ErrBatchEmpty      = errors.New("empty batch")
 	ErrVoiceNotFound   = errors.New("voice not found")
-	ErrNoAudioReceived = errors.New("no audio received")
+	ErrNoAudioReceived = communicate.ErrNoAudioReceived
+
+	ErrUnknownSubtitleFormat = errors.New("unknown subtitle format")
+
+	ErrInvalidVoice        = validate.InvalidVoiceError
+	ErrInvalidPitch        = validate.InvalidPitchError
+	ErrInvalidRate         = validate.InvalidRateError
+	ErrInvalidVolume       = validate.InvalidVolumeError
+	ErrInvalidOutputFormat = validate.InvalidOutputFormatError
+	// ErrInvalidProxy is matched by the error reported for a malformed proxy URL.
+	ErrInvalidProxy = communicate.ErrInvalidProxy
+	// ErrHTTPClientTransport reports network options that cannot be applied to the
+	// client of WithHTTPClient, as its transport is not an *http.Transport.
+	ErrHTTPClientTransport = communicate.ErrHTTPClientTransport
+
+	// ErrDialTimeout, ErrFirstByteTimeout and ErrIdleTimeout are matched through errors.Is
+	// by the TimeoutError of WithDialTimeout, WithFirstByteTimeout and WithIdleTimeout.
+	ErrDialTimeout      = communicate.ErrDialTimeout
+	ErrFirstByteTimeout = communicate.ErrFirstByteTimeout
+	ErrIdleTimeout      = communicate.ErrIdleTimeout
 )
+
+// Synthesis failures are reported as one of the following types, each carrying the
+// ConnectionId of the websocket connection and, once a turn was sent, its X-RequestId.
+// Use errors.As to inspect them.
+type (
+	// HandshakeError reports a websocket connection that could not be established.
+	// StatusCode holds the HTTP status of a rejected upgrade, or 0 if none was received.
+	HandshakeError = communicate.HandshakeError
+	// ProtocolError reports a message from the service that does not follow the protocol.
+	ProtocolError = communicate.ProtocolError
+	// CloseError reports a connection that failed or closed before a turn completed,
+	// together with its websocket close code.
+	CloseError = communicate.CloseError
+	// NoAudioError reports a turn that ended without audio. It matches ErrNoAudioReceived.
+	NoAudioError = communicate.NoAudioError
+	// ResumeError reports a chunk retried by WithRetry whose new audio does not repeat
+	// the audio already delivered for it, so that it cannot be resumed.
+	ResumeError = communicate.ResumeError
+	// WriterError reports a failure of the io.Writer receiving the audio.
+	WriterError = communicate.WriterError
+	// TimeoutError reports a dial, first byte or idle timeout; a dial timeout is wrapped
+	// in a HandshakeError.
+	TimeoutError = communicate.TimeoutError
+)
+
+// VoiceListError reports a voice list request answered with an HTTP status other than
+// 200 OK. Body holds the start of the response body.
+type VoiceListError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *VoiceListError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("list voices: unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("list voices: unexpected status %d: %s", e.StatusCode, e.Body)
+}
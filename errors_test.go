@@ -0,0 +1,39 @@
+package edgetts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestNoAudioErrorMatchesSentinel(t *testing.T) {
+	var err error = &NoAudioError{Reason: "no audio data returned by service", ConnectionID: "c1", RequestID: "r1"}
+	err = fmt.Errorf("synthesize: %w", err)
+	if !errors.Is(err, ErrNoAudioReceived) {
+		t.Fatalf("expected errors.Is to match ErrNoAudioReceived: %v", err)
+	}
+	var noAudio *NoAudioError
+	if !errors.As(err, &noAudio) || noAudio.RequestID != "r1" || noAudio.ConnectionID != "c1" {
+		t.Fatalf("expected errors.As to expose request ids, got %+v", noAudio)
+	}
+}
+
+func TestTypedErrorsUnwrap(t *testing.T) {
+	var handshake *HandshakeError
+	if !errors.As(fmt.Errorf("x: %w", &HandshakeError{StatusCode: 403, Err: io.EOF}), &handshake) || handshake.StatusCode != 403 {
+		t.Fatal("expected HandshakeError through errors.As")
+	}
+	if !errors.Is(&CloseError{Code: 1006, Err: io.ErrUnexpectedEOF}, io.ErrUnexpectedEOF) {
+		t.Fatal("expected CloseError to unwrap its cause")
+	}
+	if !errors.Is(&WriterError{Err: io.ErrShortWrite}, io.ErrShortWrite) {
+		t.Fatal("expected WriterError to unwrap its cause")
+	}
+}
+
+func TestInvalidOptionErrors(t *testing.T) {
+	if _, err := New(WithVoice("nope")).newCommunicate(Text("hi")); !errors.Is(err, ErrInvalidVoice) {
+		t.Fatalf("expected ErrInvalidVoice, got %v", err)
+	}
+}
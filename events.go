@@ -0,0 +1,172 @@
+package edgetts
+
+import (
+	"context"
+	"iter"
+
+	"github.com/lib-x/edgetts/internal/communicate"
+)
+
+// Event is one item of a synthesis event stream. It is one of *AudioChunk,
+// *WordBoundary, *SentenceBoundary, *TurnStart or *TurnEnd.
+type Event interface {
+	// ChunkIndex returns the index of the text chunk the event belongs to.
+	ChunkIndex() int
+	event()
+}
+
+// AudioChunk carries a piece of synthesized audio. Writing the Data of every chunk
+// in order yields the complete audio.
+type AudioChunk struct {
+	Index     int
+	RequestID string
+	Data      []byte
+}
+
+// WordBoundary reports when a word is spoken.
+type WordBoundary struct {
+	Index int
+	Boundary
+}
+
+// SentenceBoundary reports when a sentence is spoken; requires WithSentenceBoundaries.
+type SentenceBoundary struct {
+	Index int
+	Boundary
+}
+
+// TurnStart is reported when the service starts synthesizing a chunk. A retried
+// chunk reports a TurnStart, with a new RequestID, for every attempt.
+type TurnStart struct {
+	Index        int
+	ConnectionID string
+	RequestID    string
+}
+
+// TurnEnd is reported when the service finished synthesizing a chunk.
+type TurnEnd struct {
+	Index        int
+	ConnectionID string
+	RequestID    string
+}
+
+func (e *AudioChunk) ChunkIndex() int       { return e.Index }
+func (e *WordBoundary) ChunkIndex() int     { return e.Index }
+func (e *SentenceBoundary) ChunkIndex() int { return e.Index }
+func (e *TurnStart) ChunkIndex() int        { return e.Index }
+func (e *TurnEnd) ChunkIndex() int          { return e.Index }
+
+func (*AudioChunk) event()       {}
+func (*WordBoundary) event()     {}
+func (*SentenceBoundary) event() {}
+func (*TurnStart) event()        {}
+func (*TurnEnd) event()          {}
+
+// EventStream is a synthesis in progress. Receive from C until it is closed, then
+// check Err; or range over All. Close stops synthesis early.
+type EventStream struct {
+	// C delivers events in the order the service produced them.
+	C <-chan Event
+
+	err    error
+	cancel context.CancelFunc
+}
+
+// Err returns the error that ended the stream, if any. It is only valid after C is closed.
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close stops synthesis and releases the connection. Events already in flight are dropped.
+func (s *EventStream) Close() error {
+	s.cancel()
+	for range s.C {
+	}
+	return nil
+}
+
+// All returns an iterator over the events. A failure is yielded as a final (nil, err)
+// pair. Breaking out of the loop stops synthesis.
+func (s *EventStream) All() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for event := range s.C {
+			if !yield(event, nil) {
+				_ = s.Close()
+				return
+			}
+		}
+		if s.err != nil {
+			yield(nil, s.err)
+		}
+	}
+}
+
+// Events synthesizes req and returns its typed event stream.
+func (c *Client) Events(ctx context.Context, req Request) (*EventStream, error) {
+	if req.isEmpty() {
+		return nil, ErrEmptyInput
+	}
+	comm, err := c.newCommunicate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	internal, err := comm.Stream(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan Event)
+	stream := &EventStream{C: events, cancel: cancel}
+	go func() {
+		defer cancel()
+		defer close(events)
+		// err is written before close(events), so it is visible to readers once C is drained.
+		for e := range internal {
+			if e.Type == communicate.EventError {
+				stream.err = e.Err
+				return
+			}
+			select {
+			case events <- eventFromInternal(e):
+			case <-ctx.Done():
+				stream.err = ctx.Err()
+				return
+			}
+		}
+		stream.err = ctx.Err()
+	}()
+	return stream, nil
+}
+
+// EventSeq synthesizes req and returns an iterator over its events. Errors, including
+// ErrEmptyInput and invalid options, are yielded as a final (nil, err) pair.
+func (c *Client) EventSeq(ctx context.Context, req Request) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		stream, err := c.Events(ctx, req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		stream.All()(yield)
+	}
+}
+
+func eventFromInternal(e communicate.Event) Event {
+	switch e.Type {
+	case communicate.EventAudio:
+		return &AudioChunk{Index: e.Index, RequestID: e.RequestID, Data: e.Audio}
+	case communicate.EventBoundary:
+		boundary := boundaryFromInternal(e.Boundary)
+		if boundary.Kind == BoundarySentence {
+			return &SentenceBoundary{Index: e.Index, Boundary: boundary}
+		}
+		return &WordBoundary{Index: e.Index, Boundary: boundary}
+	case communicate.EventTurnStart:
+		return &TurnStart{Index: e.Index, ConnectionID: e.ConnectionID, RequestID: e.RequestID}
+	default:
+		return &TurnEnd{Index: e.Index, ConnectionID: e.ConnectionID, RequestID: e.RequestID}
+	}
+}
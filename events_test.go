@@ -0,0 +1,46 @@
+package edgetts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts/internal/communicate"
+)
+
+func TestEventFromInternal(t *testing.T) {
+	audio := eventFromInternal(communicate.Event{Type: communicate.EventAudio, Index: 2, RequestID: "r", Audio: []byte("abc")})
+	if chunk, ok := audio.(*AudioChunk); !ok || chunk.Index != 2 || string(chunk.Data) != "abc" {
+		t.Fatalf("unexpected audio event: %#v", audio)
+	}
+
+	word := eventFromInternal(communicate.Event{Type: communicate.EventBoundary, Index: 1, Boundary: communicate.Boundary{Type: "WordBoundary", Offset: 10_000, Text: "hi"}})
+	if wb, ok := word.(*WordBoundary); !ok || wb.Text != "hi" || wb.Offset != time.Millisecond || wb.ChunkIndex() != 1 {
+		t.Fatalf("unexpected word event: %#v", word)
+	}
+
+	sentence := eventFromInternal(communicate.Event{Type: communicate.EventBoundary, Boundary: communicate.Boundary{Type: "SentenceBoundary"}})
+	if _, ok := sentence.(*SentenceBoundary); !ok {
+		t.Fatalf("unexpected sentence event: %#v", sentence)
+	}
+
+	if _, ok := eventFromInternal(communicate.Event{Type: communicate.EventTurnStart}).(*TurnStart); !ok {
+		t.Fatal("expected turn start")
+	}
+	if _, ok := eventFromInternal(communicate.Event{Type: communicate.EventTurnEnd}).(*TurnEnd); !ok {
+		t.Fatal("expected turn end")
+	}
+}
+
+func TestEventsEmptyInput(t *testing.T) {
+	client := New()
+	if _, err := client.Events(context.Background(), Text("")); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected ErrEmptyInput, got %v", err)
+	}
+	for event, err := range client.EventSeq(context.Background(), Text("")) {
+		if event != nil || !errors.Is(err, ErrEmptyInput) {
+			t.Fatalf("expected ErrEmptyInput from iterator, got %v %v", event, err)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 
 	"github.com/lib-x/edgetts"
+	"github.com/lib-x/edgetts/ssml"
 )
 
 func ExampleSave() {
@@ -114,6 +115,23 @@ func ExampleClient_Do_ssml() {
 	_ = data
 }
 
+func ExampleClient_StreamWithBoundaries() {
+	client := edgetts.New(edgetts.WithVoice("en-US-GuyNeural"))
+	stream, boundaries, err := client.StreamWithBoundaries(context.Background(), edgetts.Text("hello world"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer stream.Close()
+
+	go func() {
+		for b := range boundaries {
+			fmt.Printf("%v %s\n", b.Offset, b.Text)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, stream)
+}
+
 func ExampleClient_SaveBatch() {
 	client := edgetts.New()
 	results, err := client.SaveBatch(context.Background(), "out", []edgetts.BatchItem{
@@ -188,3 +206,16 @@ func ExampleSSML() {
 	fmt.Println(req.Type == edgetts.InputSSML)
 	// Output: true
 }
+
+func ExampleSSML_builder() {
+	doc := ssml.NewSpeak("en-US",
+		ssml.NewVoice("en-US-AriaNeural",
+			ssml.NewExpressAs("cheerful",
+				ssml.NewProsody(ssml.Text("Tom & Jerry")).WithRate("+10%"),
+			),
+		),
+	)
+	req := edgetts.SSML(doc.String())
+	fmt.Println(req.Input)
+	// Output: <speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xmlns:mstts="https://www.w3.org/2001/mstts" xml:lang="en-US"><voice name="en-US-AriaNeural"><mstts:express-as style="cheerful"><prosody rate="+10%">Tom &amp; Jerry</prosody></mstts:express-as></voice></speak>
+}
@@ -0,0 +1,85 @@
+package edgetts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib-x/edgetts/internal/businessConsts"
+)
+
+// OutputFormat is an audio format supported by the Edge read-aloud service.
+type OutputFormat string
+
+const (
+	OutputMP3Mono24kHz48kbps  OutputFormat = businessConsts.OutputMP3Mono24kHz48kbps
+	OutputMP3Mono24kHz96kbps  OutputFormat = businessConsts.OutputMP3Mono24kHz96kbps
+	OutputMP3Mono48kHz96kbps  OutputFormat = businessConsts.OutputMP3Mono48kHz96kbps
+	OutputMP3Mono48kHz192kbps OutputFormat = businessConsts.OutputMP3Mono48kHz192kbps
+	OutputWebMOpus24kHz       OutputFormat = businessConsts.OutputWebMOpus24kHz
+	OutputOggOpus24kHz        OutputFormat = businessConsts.OutputOggOpus24kHz
+	OutputOggOpus48kHz        OutputFormat = businessConsts.OutputOggOpus48kHz
+	OutputRawPCM16kHz         OutputFormat = businessConsts.OutputRawPCM16kHz
+	OutputRawPCM24kHz         OutputFormat = businessConsts.OutputRawPCM24kHz
+	OutputRawPCM48kHz         OutputFormat = businessConsts.OutputRawPCM48kHz
+	OutputRIFFPCM16kHz        OutputFormat = businessConsts.OutputRIFFPCM16kHz
+	OutputRIFFPCM24kHz        OutputFormat = businessConsts.OutputRIFFPCM24kHz
+	OutputRIFFPCM48kHz        OutputFormat = businessConsts.OutputRIFFPCM48kHz
+)
+
+// ContentType returns the MIME type to announce when serving audio in this format.
+func (f OutputFormat) ContentType() string {
+	switch {
+	case strings.HasSuffix(string(f), "-mp3"):
+		return "audio/mpeg"
+	case strings.HasPrefix(string(f), "webm-"):
+		return "audio/webm; codecs=opus"
+	case strings.HasPrefix(string(f), "ogg-"):
+		return "audio/ogg; codecs=opus"
+	case strings.HasPrefix(string(f), "riff-"):
+		return "audio/wav"
+	case strings.HasPrefix(string(f), "raw-"):
+		return fmt.Sprintf("audio/L16; rate=%d; channels=1", f.SampleRate())
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Extension returns the conventional file extension for this format, including the dot.
+func (f OutputFormat) Extension() string {
+	switch {
+	case strings.HasSuffix(string(f), "-mp3"):
+		return ".mp3"
+	case strings.HasPrefix(string(f), "webm-"):
+		return ".webm"
+	case strings.HasPrefix(string(f), "ogg-"):
+		return ".ogg"
+	case strings.HasPrefix(string(f), "riff-"):
+		return ".wav"
+	default:
+		return ".pcm"
+	}
+}
+
+// SampleRate returns the sample rate in Hz, or 0 if it cannot be determined.
+func (f OutputFormat) SampleRate() int {
+	for _, part := range strings.Split(string(f), "-") {
+		if khz, ok := strings.CutSuffix(part, "khz"); ok {
+			n, err := strconv.Atoi(khz)
+			if err != nil {
+				return 0
+			}
+			return n * 1000
+		}
+	}
+	return 0
+}
+
+// OutputFormat returns the format req will be synthesized in once the client defaults
+// and the request options are applied.
+func (c *Client) OutputFormat(req Request) OutputFormat {
+	if format := c.mergeOptions(req.Options...).OutputFormat; format != "" {
+		return format
+	}
+	return OutputFormat(businessConsts.DefaultOutputFormat)
+}
@@ -0,0 +1,52 @@
+package edgetts
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOutputFormatMetadata(t *testing.T) {
+	cases := []struct {
+		format      OutputFormat
+		contentType string
+		ext         string
+		rate        int
+	}{
+		{OutputMP3Mono48kHz192kbps, "audio/mpeg", ".mp3", 48000},
+		{OutputWebMOpus24kHz, "audio/webm; codecs=opus", ".webm", 24000},
+		{OutputOggOpus48kHz, "audio/ogg; codecs=opus", ".ogg", 48000},
+		{OutputRIFFPCM16kHz, "audio/wav", ".wav", 16000},
+		{OutputRawPCM24kHz, "audio/L16; rate=24000; channels=1", ".pcm", 24000},
+	}
+	for _, tc := range cases {
+		if got := tc.format.ContentType(); got != tc.contentType {
+			t.Fatalf("%s: content type %q, want %q", tc.format, got, tc.contentType)
+		}
+		if got := tc.format.Extension(); got != tc.ext {
+			t.Fatalf("%s: extension %q, want %q", tc.format, got, tc.ext)
+		}
+		if got := tc.format.SampleRate(); got != tc.rate {
+			t.Fatalf("%s: sample rate %d, want %d", tc.format, got, tc.rate)
+		}
+	}
+}
+
+func TestClientOutputFormat(t *testing.T) {
+	client := New(WithOutputFormat(OutputOggOpus24kHz))
+	if got := client.OutputFormat(Text("hi")); got != OutputOggOpus24kHz {
+		t.Fatalf("unexpected client format: %s", got)
+	}
+	if got := client.OutputFormat(Text("hi", WithOutputFormat(OutputRawPCM16kHz))); got != OutputRawPCM16kHz {
+		t.Fatalf("unexpected request format: %s", got)
+	}
+	if got := New().OutputFormat(Text("hi")); got != OutputMP3Mono24kHz48kbps {
+		t.Fatalf("unexpected default format: %s", got)
+	}
+}
+
+func TestInvalidOutputFormat(t *testing.T) {
+	_, err := New(WithOutputFormat("audio-8khz-flac")).newCommunicate(Text("hi"))
+	if !errors.Is(err, ErrInvalidOutputFormat) {
+		t.Fatalf("expected InvalidOutputFormatError, got %v", err)
+	}
+}
@@ -2,20 +2,86 @@ package businessConsts
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 )
 
+// These are the defaults used when a client does not configure its own endpoint,
+// token or browser version.
 var (
 	BaseUrl            = "speech.platform.bing.com/consumer/speech/synthesize/readaloud"
 	TrustedClientToken = "6A5AA1D4EAFF4E9FB37E23D68491D6F4"
 
-	EdgeWssEndpoint   = fmt.Sprintf("wss://%s/edge/v1?TrustedClientToken=%s", BaseUrl, TrustedClientToken)
-	VoiceListEndpoint = fmt.Sprintf("https://%s/voices/list?trustedclienttoken=%s", BaseUrl, TrustedClientToken)
+	EdgeWssEndpoint   = fmt.Sprintf("wss://%s/edge/v1", BaseUrl)
+	VoiceListEndpoint = fmt.Sprintf("https://%s/voices/list", BaseUrl)
 
-	ChromiumFllVersion   = "130.0.2849.68"
-	ChromiumMajorVersion = strings.Split(ChromiumFllVersion, ".")[0]
+	ChromiumFllVersion = "130.0.2849.68"
 )
 
 const (
-	DefaultVoice = "zh-CN-XiaoxiaoNeural"
+	DefaultVoice        = "zh-CN-XiaoxiaoNeural"
+	DefaultOutputFormat = OutputMP3Mono24kHz48kbps
 )
+
+// The audio formats accepted by the read-aloud endpoint.
+const (
+	OutputMP3Mono24kHz48kbps  = "audio-24khz-48kbitrate-mono-mp3"
+	OutputMP3Mono24kHz96kbps  = "audio-24khz-96kbitrate-mono-mp3"
+	OutputMP3Mono48kHz96kbps  = "audio-48khz-96kbitrate-mono-mp3"
+	OutputMP3Mono48kHz192kbps = "audio-48khz-192kbitrate-mono-mp3"
+	OutputWebMOpus24kHz       = "webm-24khz-16bit-mono-opus"
+	OutputOggOpus24kHz        = "ogg-24khz-16bit-mono-opus"
+	OutputOggOpus48kHz        = "ogg-48khz-16bit-mono-opus"
+	OutputRawPCM16kHz         = "raw-16khz-16bit-mono-pcm"
+	OutputRawPCM24kHz         = "raw-24khz-16bit-mono-pcm"
+	OutputRawPCM48kHz         = "raw-48khz-16bit-mono-pcm"
+	OutputRIFFPCM16kHz        = "riff-16khz-16bit-mono-pcm"
+	OutputRIFFPCM24kHz        = "riff-24khz-16bit-mono-pcm"
+	OutputRIFFPCM48kHz        = "riff-48khz-16bit-mono-pcm"
+)
+
+// OutputFormats lists every supported audio format.
+var OutputFormats = []string{
+	OutputMP3Mono24kHz48kbps,
+	OutputMP3Mono24kHz96kbps,
+	OutputMP3Mono48kHz96kbps,
+	OutputMP3Mono48kHz192kbps,
+	OutputWebMOpus24kHz,
+	OutputOggOpus24kHz,
+	OutputOggOpus48kHz,
+	OutputRawPCM16kHz,
+	OutputRawPCM24kHz,
+	OutputRawPCM48kHz,
+	OutputRIFFPCM16kHz,
+	OutputRIFFPCM24kHz,
+	OutputRIFFPCM48kHz,
+}
+
+// MajorVersion returns the major component of a full browser version such as 130.0.2849.68.
+func MajorVersion(fullVersion string) string {
+	return strings.Split(fullVersion, ".")[0]
+}
+
+// UserAgent returns the Edge user agent for a full browser version.
+func UserAgent(fullVersion string) string {
+	major := MajorVersion(fullVersion)
+	return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s.0.0.0 Safari/537.36 Edg/%s.0.0.0", major, major)
+}
+
+// WithQuery appends key/value pairs to the query of endpoint, escaping the values.
+func WithQuery(endpoint string, pairs ...string) string {
+	var b strings.Builder
+	b.WriteString(endpoint)
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		b.WriteString(sep)
+		b.WriteString(pairs[i])
+		b.WriteString("=")
+		b.WriteString(url.QueryEscape(pairs[i+1]))
+		sep = "&"
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package communicate
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitText splits escaped text into chunks of at most maxBytes bytes. Each chunk ends
+// at the last paragraph break, else sentence end, else whitespace that fits. Paragraph
+// and sentence breaks in the first half of a chunk are ignored so that chunks do not get
+// needlessly small. Text without any such break is cut between runes, never inside a
+// rune or an XML entity. Elements inserted by normalizers are never split; one larger
+// than maxBytes becomes a chunk of its own. Chunks are trimmed and empty chunks are dropped.
+func splitText(text string, maxBytes int) [][]byte {
+	var chunks [][]byte
+	for len(text) > maxBytes {
+		cut := chunkCut(text, maxBytes)
+		if chunk := strings.TrimSpace(text[:cut]); chunk != "" {
+			chunks = append(chunks, []byte(chunk))
+		}
+		text = text[cut:]
+	}
+	if chunk := strings.TrimSpace(text); chunk != "" {
+		chunks = append(chunks, []byte(chunk))
+	}
+	return chunks
+}
+
+// chunkCut returns where to end the chunk starting at text, which is longer than maxBytes.
+func chunkCut(text string, maxBytes int) int {
+	cut := markupSafe(text, breakCut(text, maxBytes))
+	if cut == 0 {
+		return elementEnd(text)
+	}
+	return cut
+}
+
+// breakCut returns the preferred cut for text, ignoring markup.
+func breakCut(text string, maxBytes int) int {
+	window := text[:maxBytes]
+	// a break right at the limit is fine, so look one byte past it for whitespace
+	if len(text) > maxBytes && isSpaceByte(text[maxBytes]) {
+		window = text[:maxBytes+1]
+	}
+	half := maxBytes / 2
+
+	if i := lastParagraphEnd(window); i >= half {
+		return min(i, maxBytes)
+	}
+	if i := lastSentenceEnd(window); i >= half {
+		return min(i, maxBytes)
+	}
+	if i := strings.LastIndexFunc(window, unicode.IsSpace); i > 0 {
+		// cut after the space unless that exceeds the limit
+		if _, size := utf8.DecodeRuneInString(window[i:]); i+size <= maxBytes {
+			return i + size
+		}
+		return i
+	}
+	return safeCut(text, maxBytes)
+}
+
+// lastParagraphEnd returns the offset just after the last blank line in s, or -1.
+func lastParagraphEnd(s string) int {
+	end := -1
+	if i := strings.LastIndex(s, "\n\n"); i >= 0 {
+		end = i + 2
+	}
+	if i := strings.LastIndex(s, "\n\r\n"); i >= 0 && i+3 > end {
+		end = i + 3
+	}
+	return end
+}
+
+// lastSentenceEnd returns the offset just after the last sentence terminator in s, or -1.
+// ASCII terminators only count when followed by whitespace, so that numbers such as
+// 3.14 are not split.
+func lastSentenceEnd(s string) int {
+	for i := len(s); i > 0; {
+		r, size := utf8.DecodeLastRuneInString(s[:i])
+		switch r {
+		case '。', '！', '？', '；', '…':
+			return i
+		case '.', '!', '?', ';':
+			if i < len(s) && isSpaceByte(s[i]) {
+				return i
+			}
+		}
+		i -= size
+	}
+	return -1
+}
+
+// safeCut returns the largest offset not after maxBytes that neither splits a rune nor
+// falls inside an XML entity such as &amp; or &#12290;.
+func safeCut(text string, maxBytes int) int {
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	// entities are short; an '&' without a closing ';' before the cut starts one
+	if amp := strings.LastIndexByte(text[max(0, cut-12):cut], '&'); amp >= 0 {
+		amp += max(0, cut-12)
+		if !strings.Contains(text[amp:cut], ";") {
+			cut = amp
+		}
+	}
+	if cut == 0 {
+		// a single rune or entity longer than maxBytes; emit it whole rather than loop
+		_, size := utf8.DecodeRuneInString(text)
+		if text[0] == '&' {
+			if end := strings.IndexByte(text, ';'); end > 0 {
+				size = end + 1
+			}
+		}
+		return size
+	}
+	return cut
+}
+
+// markupSafe moves cut back to the start of the element or tag it falls in, if any.
+func markupSafe(text string, cut int) int {
+	if strings.IndexByte(text[:cut], '<') < 0 {
+		return cut
+	}
+	depth, start := 0, 0
+	for i := 0; i < cut; i++ {
+		if text[i] != '<' {
+			continue
+		}
+		if depth == 0 {
+			start = i
+		}
+		end := strings.IndexByte(text[i:], '>')
+		if end < 0 || i+end >= cut {
+			return start
+		}
+		depth += tagDepthChange(text[i : i+end+1])
+		i += end
+	}
+	if depth > 0 {
+		return start
+	}
+	return cut
+}
+
+// elementEnd returns the offset just after the element text starts with.
+func elementEnd(text string) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != '<' {
+			continue
+		}
+		end := strings.IndexByte(text[i:], '>')
+		if end < 0 {
+			break
+		}
+		depth += tagDepthChange(text[i : i+end+1])
+		i += end
+		if depth <= 0 {
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+// tagDepthChange returns 1 for an opening tag, -1 for a closing tag and 0 otherwise.
+func tagDepthChange(tag string) int {
+	switch {
+	case strings.HasPrefix(tag, "</"):
+		return -1
+	case strings.HasSuffix(tag, "/>"):
+		return 0
+	default:
+		return 1
+	}
+}
+
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -0,0 +1,99 @@
+package communicate
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func chunkStrings(text string, maxBytes int) []string {
+	var chunks []string
+	for _, chunk := range splitText(text, maxBytes) {
+		chunks = append(chunks, string(chunk))
+	}
+	return chunks
+}
+
+func TestSplitTextPrefersParagraphsThenSentencesThenWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxBytes int
+		want     []string
+	}{
+		{
+			name:     "paragraph",
+			text:     "First paragraph. Still first.\n\nSecond paragraph here.",
+			maxBytes: 40,
+			want:     []string{"First paragraph. Still first.", "Second paragraph here."},
+		},
+		{
+			name:     "sentence",
+			text:     "One two three. Four five six seven.",
+			maxBytes: 25,
+			want:     []string{"One two three.", "Four five six seven."},
+		},
+		{
+			name:     "decimal is not a sentence end",
+			text:     "Pi is 3.14159 roughly",
+			maxBytes: 12,
+			want:     []string{"Pi is", "3.14159", "roughly"},
+		},
+		{
+			name:     "CJK sentence",
+			text:     "今天天气很好。我们去公园吧！",
+			maxBytes: 30,
+			want:     []string{"今天天气很好。", "我们去公园吧！"},
+		},
+		{
+			name:     "word",
+			text:     "alpha beta gamma delta",
+			maxBytes: 12,
+			want:     []string{"alpha beta", "gamma delta"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkStrings(tt.text, tt.maxBytes)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("splitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextNeverBreaksRunesOrEntities(t *testing.T) {
+	text := strings.Repeat("中文&lt;b&gt;", 50)
+	for maxBytes := 5; maxBytes < 40; maxBytes++ {
+		chunks := chunkStrings(text, maxBytes)
+		if strings.Join(chunks, "") != text {
+			t.Fatalf("maxBytes %d: chunks do not reassemble the input", maxBytes)
+		}
+		for _, chunk := range chunks {
+			if len(chunk) > maxBytes || !utf8.ValidString(chunk) {
+				t.Fatalf("maxBytes %d: invalid chunk %q", maxBytes, chunk)
+			}
+			if i := strings.LastIndexByte(chunk, '&'); i >= 0 && !strings.Contains(chunk[i:], ";") {
+				t.Fatalf("maxBytes %d: chunk %q ends inside an entity", maxBytes, chunk)
+			}
+		}
+	}
+}
+
+func TestSplitTextKeepsElementsWhole(t *testing.T) {
+	element := `<say-as interpret-as="date" format="ymd">2024-05-01</say-as>`
+	text := "Due on " + element + " at the latest."
+	for maxBytes := 8; maxBytes < len(text); maxBytes++ {
+		chunks := chunkStrings(text, maxBytes)
+		var found bool
+		for _, chunk := range chunks {
+			if strings.Count(chunk, "<") != 2*strings.Count(chunk, "</") {
+				t.Fatalf("maxBytes %d: chunk %q splits an element", maxBytes, chunk)
+			}
+			found = found || strings.Contains(chunk, element)
+		}
+		if !found {
+			t.Fatalf("maxBytes %d: element missing from %q", maxBytes, chunks)
+		}
+	}
+}
@@ -3,20 +3,25 @@ package communicate
 import (
 	"context"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"io"
 	"net/http"
-	"sync"
+	"strings"
+	"unicode"
 
-	"github.com/gorilla/websocket"
 	"github.com/lib-x/edgetts/internal/businessConsts"
 	"github.com/lib-x/edgetts/internal/communicateOption"
+	"github.com/lib-x/edgetts/internal/transport"
 	"github.com/lib-x/edgetts/internal/validate"
 )
 
 const (
-	ssmlHeaderTemplate      = "X-RequestId:%s\r\nContent-Type:application/ssml+xml\r\nX-Timestamp:%sZ\r\nPath:ssml\r\n\r\n"
-	wordBoundaryOffset      = 8_750_000
-	binaryMessageHeaderSize = 2
+	ssmlHeaderTemplate         = "X-RequestId:%s\r\nContent-Type:application/ssml+xml\r\nX-Timestamp:%sZ\r\nPath:ssml\r\n\r\n"
+	speechConfigHeaderTemplate = "X-Timestamp:%s\r\nContent-Type:application/json; charset=utf-8\r\nPath:speech.config\r\n\r\n"
+	speechConfigBodyTemplate   = `{"context":{"synthesis":{"audio":{"metadataoptions":{"sentenceBoundaryEnabled":%t,"wordBoundaryEnabled":true},"outputFormat":"%s"}}}}` + "\r\n"
+	wordBoundaryOffset         = 8_750_000
+	binaryMessageHeaderSize    = 2
 )
 
 type InputType int
@@ -26,26 +31,18 @@ const (
 	InputSSML
 )
 
-var (
-	headerOnce        = &sync.Once{}
-	communicateHeader http.Header
-)
-
-func init() {
-	headerOnce.Do(func() {
-		communicateHeader = makeDefaultHeaders()
-	})
-}
-
 type Communicate struct {
 	inputType InputType
 	input     string
-
-	audioDataIndex int
-	prevIdx        int
-	shiftTime      int
-	finalUtterance map[int]int
-	opt            *communicateOption.CommunicateOption
+	// spans, if set, are the runs of a text input in different languages.
+	spans []Span
+	// connectionID and requestID identify the current connection and the X-RequestId
+	// of the turn being synthesized on it.
+	connectionID string
+	requestID    string
+	clock        *SkewClock
+	transport    transport.Transport
+	opt          *communicateOption.CommunicateOption
 }
 
 type textEntry struct {
@@ -69,28 +66,74 @@ type metaDataContext struct {
 	Metadata []metaDataEntry `json:"Metadata"`
 }
 
-type audioData struct {
-	Data  []byte
-	Index int
+// Boundary is a timing event reported by the service through audio.metadata frames.
+// Offset and Duration are expressed in 100-nanosecond ticks. The service reports Offset
+// relative to the current chunk; Stream shifts it to the start of the whole audio.
+type Boundary struct {
+	Type     string
+	Offset   int
+	Duration int
+	Text     string
 }
 
-type unknownResponse struct {
-	Message string
+// turnState tracks the progress of one synthesis turn on a connection.
+type turnState struct {
+	// started is set by turn.start; binary audio is only expected afterwards.
+	started       bool
+	ended         bool
+	audioReceived bool
+
+	// progress is shared by every attempt at the same chunk. audioSeen and
+	// boundariesSeen count what this attempt received, so that a retried turn
+	// only forwards what earlier attempts did not deliver. replay hashes the audio
+	// this attempt repeats, to be compared with progress.digest.
+	progress       *chunkProgress
+	audioSeen      int
+	boundariesSeen int
+	replay         hash.Hash64
 }
 
-type unexpectedResponse struct {
-	Message string
+// chunkProgress records how much of a chunk has already been delivered to the output.
+type chunkProgress struct {
+	audioBytes int
+	// digest hashes the audioBytes delivered.
+	digest     hash.Hash64
+	boundaries int
+	// end is the furthest boundary end seen for the chunk, in ticks, padded by
+	// wordBoundaryOffset. It estimates the chunk duration when meter is unavailable.
+	end int
+	// meter measures the duration of the delivered audio.
+	meter audioMeter
 }
 
-type noAudioReceived struct {
-	Message string
+func (c *Communicate) newChunkProgress() *chunkProgress {
+	return &chunkProgress{digest: fnv.New64a(), meter: newAudioMeter(c.opt.OutputFormat)}
 }
 
-type webSocketError struct {
-	Message string
+// duration returns the length of the chunk in ticks, by which the boundaries of the
+// following chunk are shifted. It is measured from the audio when the format allows.
+func (p *chunkProgress) duration() int {
+	if p.meter != nil {
+		if ticks := p.meter.ticks(); ticks > 0 {
+			return ticks
+		}
+	}
+	return p.end
 }
 
-var errNoAudioReceived = fmt.Errorf("no audio received")
+// turnResult describes how a turn ended and whether its connection can be reused.
+type turnResult int
+
+const (
+	// turnCompleted means turn.end was received and the connection can carry the next turn.
+	turnCompleted turnResult = iota
+	// turnConnectionLost means the connection closed before the turn produced anything.
+	turnConnectionLost
+	// turnInterrupted means the connection closed after audio was received.
+	turnInterrupted
+	// turnFailed means an error was reported on the output channel or ctx was cancelled.
+	turnFailed
+)
 
 func NewCommunicate(inputType InputType, input string, opt *communicateOption.CommunicateOption) (*Communicate, error) {
 	if opt == nil {
@@ -101,11 +144,40 @@ func NewCommunicate(inputType InputType, input string, opt *communicateOption.Co
 	if err := validate.WithCommunicateOption(opt); err != nil {
 		return nil, err
 	}
-	return &Communicate{
+	c := &Communicate{
 		inputType: inputType,
 		input:     input,
 		opt:       opt,
-	}, nil
+		clock:     serviceClock,
+	}
+	t, err := c.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	c.transport = t
+	return c, nil
+}
+
+// Span is a run of text in Lang, a locale such as en-US. An empty Lang is the language
+// of the voice.
+type Span struct {
+	Lang string
+	Text string
+}
+
+// NewCommunicateSpans returns a Communicate for a text input made of spans in different
+// languages.
+func NewCommunicateSpans(spans []Span, opt *communicateOption.CommunicateOption) (*Communicate, error) {
+	var input strings.Builder
+	for _, span := range spans {
+		input.WriteString(span.Text)
+	}
+	c, err := NewCommunicate(InputText, input.String(), opt)
+	if err != nil {
+		return nil, err
+	}
+	c.spans = spans
+	return c, nil
 }
 
 // WriteStreamTo writes audio to w using a background context.
@@ -116,153 +188,309 @@ func (c *Communicate) WriteStreamTo(w io.Writer) error {
 
 // WriteStreamToContext writes audio to w and returns written bytes.
 func (c *Communicate) WriteStreamToContext(ctx context.Context, w io.Writer) (int64, error) {
+	return c.WriteStreamWithBoundaries(ctx, w, nil)
+}
+
+// WriteStreamWithBoundaries writes audio to w and reports every boundary to onBoundary.
+// onBoundary may be nil, in which case boundaries are discarded.
+func (c *Communicate) WriteStreamWithBoundaries(ctx context.Context, w io.Writer, onBoundary func(Boundary)) (int64, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	output, err := c.stream(ctx)
+	events, err := c.Stream(ctx)
 	if err != nil {
 		return 0, err
 	}
 
 	var written int64
-	for payload := range output {
-		if errVal, ok := payload["error"]; ok {
-			switch v := errVal.(type) {
-			case webSocketError:
-				return written, fmt.Errorf("websocket error: %s", v.Message)
-			case unknownResponse:
-				return written, fmt.Errorf("unknown response: %s", v.Message)
-			case unexpectedResponse:
-				return written, fmt.Errorf("unexpected response: %s", v.Message)
-			case noAudioReceived:
-				return written, fmt.Errorf("%w: %s", errNoAudioReceived, v.Message)
-			default:
-				return written, fmt.Errorf("stream error: %v", v)
-			}
-		}
-		if t, isTypedData := payload["type"]; isTypedData && t == "audio" {
-			data, ok := payload["data"].(audioData)
-			if !ok {
-				continue
-			}
-			n, err := w.Write(data.Data)
+	for event := range events {
+		switch event.Type {
+		case EventError:
+			return written, event.Err
+		case EventAudio:
+			n, err := w.Write(event.Audio)
 			written += int64(n)
 			if err != nil {
-				return written, fmt.Errorf("write audio payload: %w", err)
+				return written, &WriterError{ConnectionID: event.ConnectionID, RequestID: event.RequestID, Err: err}
+			}
+		case EventBoundary:
+			if onBoundary != nil {
+				onBoundary(event.Boundary)
 			}
 		}
 	}
-	return written, nil
+	return written, ctx.Err()
 }
 
-func makeDefaultHeaders() http.Header {
+func makeDefaultHeaders(browserVersion string) http.Header {
 	header := make(http.Header)
 	header.Set("Pragma", "no-cache")
 	header.Set("Cache-Control", "no-cache")
 	header.Set("Origin", "chrome-extension://jdiccldimpdaibmpdkjnbmckianbfold")
 	header.Set("Accept-Encoding", "gzip, deflate, br")
 	header.Set("Accept-Language", "en-US,en;q=0.9")
-	header.Set("User-Agent", fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s.0.0.0 Safari/537.36  Edg/%s.0.0.0",
-		businessConsts.ChromiumMajorVersion,
-		businessConsts.ChromiumMajorVersion,
-	))
+	header.Set("User-Agent", businessConsts.UserAgent(browserVersion))
 	return header
 }
 
-func (c *Communicate) sendSpeechGenerationConfig(conn *websocket.Conn, currentTime string) error {
-	return conn.WriteMessage(websocket.TextMessage, []byte(
-		"X-Timestamp:"+currentTime+"\r\n"+
-			"Content-Type:application/json; charset=utf-8\r\n"+
-			"Path:speech.config\r\n\r\n"+
-			`{"context":{"synthesis":{"audio":{"metadataoptions":{"sentenceBoundaryEnabled":false,"wordBoundaryEnabled":true},"outputFormat":"audio-24khz-48kbitrate-mono-mp3"}}}}`+"\r\n",
+func (c *Communicate) sendSpeechGenerationConfig(conn transport.Conn, currentTime string) error {
+	return conn.WriteMessage(transport.TextMessage, []byte(
+		fmt.Sprintf(speechConfigHeaderTemplate, currentTime)+
+			fmt.Sprintf(speechConfigBodyTemplate, c.opt.SentenceBoundaryEnabled, c.opt.OutputFormat),
 	))
 }
 
-func (c *Communicate) sendSSML(conn *websocket.Conn, currentTime string, text []byte) error {
-	payload := string(text)
-	if c.inputType == InputText {
-		payload = makeSsml(string(text), c.opt.Pitch, c.opt.Voice, c.opt.Rate, c.opt.Volume)
+func (c *Communicate) sendSSML(conn transport.Conn, currentTime string, text []byte) error {
+	payload := c.ssmlOf(text)
+	c.requestID = generateConnectID()
+	return conn.WriteMessage(transport.TextMessage,
+		[]byte(appendRequestContextToSsmlHeaders(c.requestID, currentTime, payload)))
+}
+
+// connect dials the service and sends the speech configuration, which applies to
+// every turn sent over the returned connection.
+func (c *Communicate) connect(ctx context.Context) (transport.Conn, error) {
+	conn, resp, err := c.dial(ctx)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusForbidden && c.clock.SyncWith(resp.Header.Get("Date")) {
+		// the token was most likely rejected because the local clock drifted; the
+		// clock is now corrected from the server's Date header, so try once more.
+		conn, resp, err = c.dial(ctx)
+	}
+	if err != nil {
+		handshakeErr := &HandshakeError{ConnectionID: c.connectionID, Err: err}
+		if resp != nil {
+			handshakeErr.StatusCode = resp.StatusCode
+		}
+		return nil, handshakeErr
+	}
+	if err := c.sendSpeechGenerationConfig(conn, timestampInMST(c.clock.Now())); err != nil {
+		_ = conn.Close()
+		return nil, c.newCloseError(err)
 	}
-	return conn.WriteMessage(websocket.TextMessage,
-		[]byte(appendRequestContextToSsmlHeaders(generateConnectID(), currentTime, payload)))
+	return conn, nil
 }
 
-func (c *Communicate) stream(ctx context.Context) (chan map[string]interface{}, error) {
-	output := make(chan map[string]interface{})
+// Stream synthesizes the input and returns its events. The channel is closed once
+// synthesis finishes; a failure is reported as a final EventError. Cancelling ctx
+// stops synthesis and closes the channel.
+func (c *Communicate) Stream(ctx context.Context) (<-chan Event, error) {
+	events := make(chan Event)
+	output := channelSink{ctx: ctx, ch: events}
 	texts := c.buildPayloads()
-	c.audioDataIndex = len(texts)
-	c.finalUtterance = make(map[int]int)
-	c.prevIdx = -1
-	c.shiftTime = -1
 	go func() {
-		defer close(output)
-		for idx, text := range texts {
-			func() {
-				wsURL := generateWssEndpoint()
-				dialer := websocket.Dialer{}
-				c.applyWebSocketProxyIfSet(&dialer)
-				conn, _, err := dialer.Dial(wsURL, communicateHeader)
-				if err != nil {
-					output <- map[string]interface{}{"error": webSocketError{Message: err.Error()}}
-					return
-				}
-				defer conn.Close()
+		defer close(events)
+		if c.opt.ChunkConcurrency > 1 && len(texts) > 1 {
+			c.streamConcurrently(ctx, output, texts)
+			return
+		}
+		c.streamSequentially(ctx, output, texts)
+	}()
 
-				currentTime := currentTimeInMST()
-				if err := c.sendSpeechGenerationConfig(conn, currentTime); err != nil {
-					output <- map[string]interface{}{"error": unexpectedResponse{Message: err.Error()}}
-					return
-				}
-				if err := c.sendSSML(conn, currentTime, text); err != nil {
-					output <- map[string]interface{}{"error": unexpectedResponse{Message: err.Error()}}
-					return
-				}
-				c.connStreamExchange(ctx, conn, output, idx)
-			}()
+	return events, nil
+}
+
+// streamSequentially synthesizes the chunks one after another over a shared connection.
+func (c *Communicate) streamSequentially(ctx context.Context, output eventSink, texts [][]byte) {
+	var conn transport.Conn
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
 		}
 	}()
 
-	return output, nil
+	shift := 0
+	for idx, text := range texts {
+		progress := c.newChunkProgress()
+		if !c.synthesizeChunk(ctx, &conn, shiftedSink{eventSink: output, shift: shift}, idx, text, progress) {
+			return
+		}
+		shift += progress.duration()
+	}
+}
+
+// synthesizeChunk synthesizes one chunk on *conn, dialing a new connection when needed
+// and retrying retryable failures according to the retry policy. progress is updated
+// with what was delivered. It reports false once an error has been sent to output and
+// streaming must stop.
+func (c *Communicate) synthesizeChunk(ctx context.Context, conn *transport.Conn, output eventSink, idx int, text []byte, progress *chunkProgress) bool {
+	retries := 0
+	for {
+		reused := *conn != nil
+		if !reused {
+			newConn, err := c.connect(ctx)
+			if err != nil {
+				if c.backoff(ctx, err, &retries) {
+					continue
+				}
+				output.send(errorEvent(err))
+				return false
+			}
+			*conn = newConn
+		}
+
+		result, err := c.synthesizeTurn(ctx, *conn, output, idx, text, progress)
+		switch result {
+		case turnCompleted:
+			return true
+		case turnFailed:
+			return false
+		}
+
+		_ = (*conn).Close()
+		*conn = nil
+		if result == turnConnectionLost && reused {
+			// the service closed the idle connection between turns; this does not count as a retry.
+			continue
+		}
+		if c.backoff(ctx, err, &retries) {
+			continue
+		}
+		output.send(errorEvent(c.newCloseError(err)))
+		return false
+	}
+}
+
+// dial opens a new connection, giving up after the dial timeout if one is set.
+func (c *Communicate) dial(ctx context.Context) (transport.Conn, *http.Response, error) {
+	c.connectionID = generateConnectID()
+	c.requestID = ""
+	if c.opt.DialTimeout <= 0 {
+		return c.transport.Dial(ctx, c.wssEndpoint(c.clock.Now()), makeDefaultHeaders(c.opt.BrowserVersion))
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, c.opt.DialTimeout)
+	defer cancel()
+	conn, resp, err := c.transport.Dial(dialCtx, c.wssEndpoint(c.clock.Now()), makeDefaultHeaders(c.opt.BrowserVersion))
+	// the dialer may report its own i/o timeout just before dialCtx expires
+	if err != nil && ctx.Err() == nil && (dialCtx.Err() != nil || isTimeout(err)) {
+		err = c.newTimeoutError(ErrDialTimeout, c.opt.DialTimeout)
+	}
+	return conn, resp, err
+}
+
+// synthesizeTurn sends one chunk as a new turn on conn and relays its responses.
+func (c *Communicate) synthesizeTurn(ctx context.Context, conn transport.Conn, output eventSink, idx int, text []byte, progress *chunkProgress) (turnResult, error) {
+	if err := c.sendSSML(conn, timestampInMST(c.clock.Now()), text); err != nil {
+		return turnConnectionLost, err
+	}
+	return c.connStreamExchange(ctx, conn, output, idx, progress)
+}
+
+// SSML returns the SSML document of every turn the input is sent as, without
+// connecting to the service.
+func (c *Communicate) SSML() []string {
+	payloads := c.buildPayloads()
+	documents := make([]string, len(payloads))
+	for i, payload := range payloads {
+		documents[i] = c.ssmlOf(payload)
+	}
+	return documents
+}
+
+// ssmlOf returns the SSML document of one chunk of the input.
+func (c *Communicate) ssmlOf(chunk []byte) string {
+	if c.inputType == InputText {
+		return makeSsml(string(chunk), c.opt.Pitch, c.opt.Voice, c.opt.Rate, c.opt.Volume)
+	}
+	return string(chunk)
 }
 
 func (c *Communicate) buildPayloads() [][]byte {
 	switch c.inputType {
 	case InputSSML:
-		return [][]byte{[]byte(c.input)}
+		return splitSSML(c.input, c.maxChunkBytes(getMaxSSMLSize()))
 	default:
-		return splitTextByByteLength(
-			escape(removeIncompatibleCharacters(c.input)),
-			getMaxMessageSize(c.opt.Pitch, c.opt.Voice, c.opt.Rate, c.opt.Volume),
-		)
+		maxBytes := c.maxChunkBytes(getMaxMessageSize(c.opt.Pitch, c.opt.Voice, c.opt.Rate, c.opt.Volume))
+		return splitText(c.markup(maxBytes), maxBytes)
+	}
+}
+
+// maxChunkBytes returns limit, or MaxChunkBytes if that is smaller.
+func (c *Communicate) maxChunkBytes(limit int) int {
+	if c.opt.MaxChunkBytes > 0 && c.opt.MaxChunkBytes < limit {
+		return c.opt.MaxChunkBytes
+	}
+	return limit
+}
+
+// markup returns the escaped and normalized text input. Spans in another language than
+// the voice are wrapped in lang elements of at most maxBytes when the voice is
+// multilingual; other voices read them in their own language.
+func (c *Communicate) markup(maxBytes int) string {
+	spans := c.spans
+	if spans == nil {
+		spans = []Span{{Text: c.input}}
+	}
+	lang := voiceLocale(c.opt.Voice)
+	multilingual := isMultilingual(c.opt.Voice)
+
+	var b strings.Builder
+	for _, span := range spans {
+		text := escape(removeIncompatibleCharacters(span.Text))
+		for _, normalize := range c.opt.Normalizers {
+			text = normalize(text)
+		}
+		if !multilingual || span.Lang == "" || strings.EqualFold(span.Lang, lang) {
+			b.WriteString(text)
+			continue
+		}
+
+		// keep the whitespace around the span outside of the lang element
+		trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+		b.WriteString(text[:len(text)-len(trimmed)])
+		body := strings.TrimRightFunc(trimmed, unicode.IsSpace)
+		open := `<lang xml:lang="` + escape(span.Lang) + `">`
+		for i, piece := range splitText(body, max(1, maxBytes-len(open)-len("</lang>"))) {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(open)
+			b.Write(piece)
+			b.WriteString("</lang>")
+		}
+		b.WriteString(trimmed[len(body):])
 	}
+	return b.String()
 }
 
-func (c *Communicate) connStreamExchange(ctx context.Context, conn *websocket.Conn, output chan map[string]interface{}, idx int) {
-	downloadAudio := false
-	audioWasReceived := false
+// connStreamExchange relays the responses of one turn. Cancelling ctx or exceeding
+// the first byte or idle timeout closes conn, which interrupts a pending read.
+func (c *Communicate) connStreamExchange(ctx context.Context, conn transport.Conn, output eventSink, idx int, progress *chunkProgress) (turnResult, error) {
+	state := &turnState{progress: progress}
+	watchdog := newReadWatchdog(ctx, conn)
+	defer watchdog.stop()
+	watchdog.arm(c.opt.FirstByteTimeout, c.newTimeoutError(ErrFirstByteTimeout, c.opt.FirstByteTimeout))
+	idleErr := c.newTimeoutError(ErrIdleTimeout, c.opt.IdleTimeout)
 
 	for {
-		select {
-		case <-ctx.Done():
-			if !audioWasReceived {
-				output <- map[string]interface{}{"error": noAudioReceived{Message: "context cancelled before audio was received"}}
+		msgType, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return turnFailed, ctx.Err()
 			}
-			return
-		default:
-			msgType, message, err := conn.ReadMessage()
-			if err != nil {
-				if !audioWasReceived {
-					output <- map[string]interface{}{"error": webSocketError{Message: err.Error()}}
-				}
-				return
+			if cause := watchdog.cause(); cause != nil {
+				// a timeout always counts as a failed attempt, even on a reused connection
+				return turnInterrupted, cause
 			}
-			continueProcessing := c.handleWebSocketMessage(msgType, message, output, idx, &downloadAudio, &audioWasReceived)
-			if !continueProcessing {
-				if !audioWasReceived {
-					output <- map[string]interface{}{"error": noAudioReceived{Message: "no audio data returned by service"}}
-				}
-				return
+			if state.audioReceived || state.started {
+				return turnInterrupted, err
+			}
+			return turnConnectionLost, err
+		}
+		continueProcessing := c.handleWebSocketMessage(msgType, message, output, idx, state)
+		if continueProcessing {
+			if state.audioReceived {
+				watchdog.arm(c.opt.IdleTimeout, idleErr)
 			}
+			continue
+		}
+		if !state.ended {
+			return turnFailed, nil
+		}
+		if !state.audioReceived {
+			output.send(errorEvent(c.newNoAudioError("no audio data returned by service")))
+			return turnFailed, nil
 		}
+		return turnCompleted, nil
 	}
 }
@@ -0,0 +1,169 @@
+package communicate
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lib-x/edgetts/internal/transport"
+)
+
+// chunkBuffer holds the events of one chunk until every chunk before it has been
+// forwarded. A worker appends to it while the forwarding loop drains it, so the chunk
+// being forwarded still streams as it is synthesized.
+type chunkBuffer struct {
+	progress *chunkProgress
+
+	mu     sync.Mutex
+	events []Event
+	done   bool
+	// notify wakes the reader after an append or close.
+	notify chan struct{}
+}
+
+func newChunkBuffer(progress *chunkProgress) *chunkBuffer {
+	return &chunkBuffer{progress: progress, notify: make(chan struct{}, 1)}
+}
+
+func (b *chunkBuffer) send(e Event) bool {
+	b.mu.Lock()
+	b.events = append(b.events, e)
+	b.mu.Unlock()
+	b.wake()
+	return true
+}
+
+// close marks the chunk as finished; progress must not change afterwards.
+func (b *chunkBuffer) close() {
+	b.mu.Lock()
+	b.done = true
+	b.mu.Unlock()
+	b.wake()
+}
+
+func (b *chunkBuffer) wake() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next event of the chunk, waiting for it if needed. It reports
+// false once the chunk is finished and drained, or when ctx is done.
+func (b *chunkBuffer) next(ctx context.Context) (Event, bool) {
+	for {
+		b.mu.Lock()
+		if len(b.events) > 0 {
+			e := b.events[0]
+			b.events[0] = Event{}
+			b.events = b.events[1:]
+			b.mu.Unlock()
+			return e, true
+		}
+		done := b.done
+		b.mu.Unlock()
+		if done {
+			return Event{}, false
+		}
+		select {
+		case <-b.notify:
+		case <-ctx.Done():
+			return Event{}, false
+		}
+	}
+}
+
+// streamConcurrently synthesizes up to ChunkConcurrency chunks at once, each worker on
+// its own connection, and forwards their events to output in chunk order. No more than
+// PrefetchWindow chunks are started ahead of the one being forwarded, which bounds the
+// audio held in memory.
+func (c *Communicate) streamConcurrently(ctx context.Context, output eventSink, texts [][]byte) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buffers := make([]*chunkBuffer, len(texts))
+	for idx := range buffers {
+		buffers[idx] = newChunkBuffer(c.newChunkProgress())
+	}
+	// slots holds one token for every chunk dispatched but not yet forwarded.
+	slots := make(chan struct{}, max(c.opt.PrefetchWindow, 1))
+	jobs := make(chan int)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for idx := range texts {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- idx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for range min(c.opt.ChunkConcurrency, len(texts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.worker().synthesizeJobs(ctx, jobs, texts, buffers)
+		}()
+	}
+
+	shift := 0
+	for _, buffer := range buffers {
+		sink := shiftedSink{eventSink: output, shift: shift}
+		for {
+			e, ok := buffer.next(ctx)
+			if !ok {
+				break
+			}
+			if !sink.send(e) || e.Type == EventError {
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		shift += buffer.progress.duration()
+		<-slots
+	}
+}
+
+// worker returns a Communicate sharing c's input and options but with its own
+// connection state, so that it can synthesize chunks alongside c.
+func (c *Communicate) worker() *Communicate {
+	return &Communicate{
+		inputType: c.inputType,
+		input:     c.input,
+		spans:     c.spans,
+		clock:     c.clock,
+		transport: c.transport,
+		opt:       c.opt,
+	}
+}
+
+// synthesizeJobs synthesizes the chunks received from jobs into their buffers, reusing
+// one connection, until jobs is closed or a chunk fails.
+func (c *Communicate) synthesizeJobs(ctx context.Context, jobs <-chan int, texts [][]byte, buffers []*chunkBuffer) {
+	var conn transport.Conn
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	for idx := range jobs {
+		buffer := buffers[idx]
+		ok := c.synthesizeChunk(ctx, &conn, buffer, idx, texts[idx], buffer.progress)
+		buffer.close()
+		if !ok {
+			return
+		}
+	}
+}
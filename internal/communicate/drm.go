@@ -3,23 +3,87 @@ package communicate
 import (
 	"crypto/sha256"
 	"fmt"
-	"github.com/lib-x/edgetts/internal/businessConsts"
+	"net/http"
+	"sync/atomic"
 	"time"
+
+	"github.com/lib-x/edgetts/internal/businessConsts"
 )
 
-func generateWssEndpoint() string {
-	return businessConsts.EdgeWssEndpoint +
-		"&Sec-MS-GEC=" + generateSecMsGecToken() +
-		"&Sec-MS-GEC-Version=" + generateSecMsGecVersion() +
-		"&ConnectionId=" + generateConnectID()
+// clockSyncTolerance is the smallest difference from the server clock worth correcting.
+// Tokens are derived from five minute windows, so anything below a few seconds is noise
+// caused by the one second resolution of the Date header.
+const clockSyncTolerance = 5 * time.Second
+
+// Clock provides the current time used to derive Sec-MS-GEC tokens and timestamps.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SkewClock corrects a base clock by the offset observed from the service's Date header.
+// It is safe for concurrent use.
+type SkewClock struct {
+	base   Clock
+	offset atomic.Int64
+}
+
+// NewSkewClock returns a SkewClock over base, or over the system clock if base is nil.
+func NewSkewClock(base Clock) *SkewClock {
+	if base == nil {
+		base = systemClock{}
+	}
+	return &SkewClock{base: base}
+}
+
+// serviceClock is shared by all connections since clock drift is a property of the host.
+var serviceClock = NewSkewClock(nil)
+
+// Now returns the corrected time.
+func (c *SkewClock) Now() time.Time {
+	return c.base.Now().Add(c.Offset())
+}
+
+// Offset returns the current correction applied to the base clock.
+func (c *SkewClock) Offset() time.Duration {
+	return time.Duration(c.offset.Load())
+}
+
+// SyncWith updates the correction from an HTTP Date header value. It reports whether the
+// correction changed enough that a token generated before the call is likely stale.
+func (c *SkewClock) SyncWith(date string) bool {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return false
+	}
+	offset := serverTime.Sub(c.base.Now())
+	if diff := offset - c.Offset(); diff > -clockSyncTolerance && diff < clockSyncTolerance {
+		return false
+	}
+	c.offset.Store(int64(offset))
+	return true
+}
+
+// wssEndpoint returns the configured endpoint with the token, the Sec-MS-GEC values
+// for now and the current connection ID.
+func (c *Communicate) wssEndpoint(now time.Time) string {
+	return businessConsts.WithQuery(c.opt.Endpoint,
+		"TrustedClientToken", c.opt.TrustedClientToken,
+		"Sec-MS-GEC", generateSecMsGecToken(now, c.opt.TrustedClientToken),
+		"Sec-MS-GEC-Version", generateSecMsGecVersion(c.opt.BrowserVersion),
+		"ConnectionId", c.connectionID,
+	)
 }
 
-func generateSecMsGecToken() string {
-	now := time.Now().UTC()
+func generateSecMsGecToken(now time.Time, trustedClientToken string) string {
+	now = now.UTC()
 	ticks := (now.Unix() + 11644473600) * 10000000
 	ticks = ticks - (ticks % 3_000_000_000)
 
-	strToHash := fmt.Sprintf("%d%s", ticks, businessConsts.TrustedClientToken)
+	strToHash := fmt.Sprintf("%d%s", ticks, trustedClientToken)
 	hash := sha256.New()
 	hash.Write([]byte(strToHash))
 	hexDig := fmt.Sprintf("%X", hash.Sum(nil))
@@ -27,6 +91,6 @@ func generateSecMsGecToken() string {
 }
 
 // generateSecMsGecVersion  Sec-MS-GEC-Version token
-func generateSecMsGecVersion() string {
-	return fmt.Sprintf("1-%s", businessConsts.ChromiumFllVersion)
+func generateSecMsGecVersion(browserVersion string) string {
+	return fmt.Sprintf("1-%s", browserVersion)
 }
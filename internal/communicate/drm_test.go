@@ -0,0 +1,88 @@
+package communicate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lib-x/edgetts/internal/communicateOption"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestSkewClockSyncWith(t *testing.T) {
+	local := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	clock := NewSkewClock(fixedClock(local))
+
+	if clock.SyncWith("not a date") {
+		t.Fatal("expected invalid date to be ignored")
+	}
+	if clock.SyncWith(local.Add(2 * time.Second).Format(http.TimeFormat)) {
+		t.Fatal("expected small drift to be ignored")
+	}
+	if !clock.SyncWith(local.Add(-10 * time.Minute).Format(http.TimeFormat)) {
+		t.Fatal("expected large drift to be corrected")
+	}
+	if got := clock.Now(); !got.Equal(local.Add(-10 * time.Minute)) {
+		t.Fatalf("unexpected corrected time: %v", got)
+	}
+	if clock.SyncWith(local.Add(-10 * time.Minute).Format(http.TimeFormat)) {
+		t.Fatal("expected an unchanged offset not to report a correction")
+	}
+}
+
+func TestGenerateSecMsGecTokenWindow(t *testing.T) {
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	if generateSecMsGecToken(base, "token") != generateSecMsGecToken(base.Add(4*time.Minute), "token") {
+		t.Fatal("expected tokens within one five minute window to match")
+	}
+	if generateSecMsGecToken(base, "token") == generateSecMsGecToken(base.Add(5*time.Minute), "token") {
+		t.Fatal("expected tokens in different windows to differ")
+	}
+}
+
+func TestConnectRetriesWithServerClock(t *testing.T) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	// the server lives in the middle of a token window, an hour ahead of the client, so
+	// that the token it expects does not depend on when the test runs
+	serverTime := time.Date(2026, 1, 1, 12, 2, 30, 0, time.UTC)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Query().Get("Sec-MS-GEC") != generateSecMsGecToken(serverTime, "test") {
+			w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = conn.Close()
+	}))
+	defer server.Close()
+
+	c, err := NewCommunicate(InputText, "hello", &communicateOption.CommunicateOption{
+		Endpoint:           "ws" + strings.TrimPrefix(server.URL, "http") + "/",
+		TrustedClientToken: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.clock = NewSkewClock(fixedClock(serverTime.Add(-time.Hour)))
+
+	conn, err := c.connect(context.Background())
+	if err != nil {
+		t.Fatalf("expected connect to succeed after clock correction, got %v", err)
+	}
+	_ = conn.Close()
+	if attempts != 2 {
+		t.Fatalf("expected 2 handshake attempts, got %d", attempts)
+	}
+}
@@ -0,0 +1,187 @@
+package communicate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// ticksPerSecond is the number of 100-nanosecond ticks in a second.
+const ticksPerSecond = 10_000_000
+
+// audioMeter measures the playback duration of the audio of a chunk as it arrives.
+type audioMeter interface {
+	write(p []byte)
+	// ticks returns the duration written so far in 100ns ticks, or 0 if it is unknown.
+	ticks() int
+}
+
+// newAudioMeter returns a meter for outputFormat, or nil for formats whose duration
+// cannot be measured from the byte stream, such as Opus.
+func newAudioMeter(outputFormat string) audioMeter {
+	switch {
+	case strings.HasSuffix(outputFormat, "-mp3"):
+		return &mp3Meter{}
+	case strings.HasPrefix(outputFormat, "raw-"):
+		return &pcmMeter{sampleRate: sampleRateOf(outputFormat)}
+	case strings.HasPrefix(outputFormat, "riff-"):
+		return &pcmMeter{sampleRate: sampleRateOf(outputFormat), riff: true}
+	default:
+		return nil
+	}
+}
+
+// sampleRateOf extracts the sample rate from a format name such as raw-24khz-16bit-mono-pcm.
+func sampleRateOf(outputFormat string) int {
+	for _, part := range strings.Split(outputFormat, "-") {
+		if khz, ok := strings.CutSuffix(part, "khz"); ok {
+			n, _ := strconv.Atoi(khz)
+			return n * 1000
+		}
+	}
+	return 0
+}
+
+// pcmMeter measures 16-bit mono PCM. With riff set, the WAV header that starts the
+// audio of every turn is not counted.
+type pcmMeter struct {
+	sampleRate int
+	riff       bool
+	bytes      int
+	started    bool
+}
+
+func (m *pcmMeter) write(p []byte) {
+	if m.riff && !m.started {
+		p = p[min(riffHeaderLength(p), len(p)):]
+	}
+	m.started = true
+	m.bytes += len(p)
+}
+
+func (m *pcmMeter) ticks() int {
+	if m.sampleRate == 0 {
+		return 0
+	}
+	return int(int64(m.bytes/2) * ticksPerSecond / int64(m.sampleRate))
+}
+
+// riffHeaderLength returns the offset of the samples in a WAV file, assuming the
+// canonical 44-byte header when the data chunk cannot be found in p.
+func riffHeaderLength(p []byte) int {
+	if len(p) < 12 || !bytes.Equal(p[:4], []byte("RIFF")) {
+		return 0
+	}
+	for offset := 12; offset+8 <= len(p); {
+		size := int(binary.LittleEndian.Uint32(p[offset+4 : offset+8]))
+		if bytes.Equal(p[offset:offset+4], []byte("data")) {
+			return offset + 8
+		}
+		offset += 8 + size + size%2
+	}
+	return 44
+}
+
+// mp3Meter counts the samples of MPEG audio layer III frames. Frames may be split
+// across writes: skip is what remains of the current frame and pending holds an
+// incomplete header.
+type mp3Meter struct {
+	pending []byte
+	skip    int
+
+	// samples at sampleRate are added to measured when the rate changes.
+	measured   int64
+	samples    int64
+	sampleRate int
+}
+
+func (m *mp3Meter) write(p []byte) {
+	if m.skip >= len(p) {
+		m.skip -= len(p)
+		return
+	}
+	p = p[m.skip:]
+	m.skip = 0
+
+	data := p
+	if len(m.pending) > 0 {
+		data = append(m.pending, p...)
+	}
+	for len(data) >= 4 {
+		frameLength, samples, sampleRate, ok := parseMP3FrameHeader(data)
+		if !ok {
+			// not a frame header; resynchronize on the next byte
+			data = data[1:]
+			continue
+		}
+		if sampleRate != m.sampleRate {
+			m.measured += m.tickCount()
+			m.samples = 0
+			m.sampleRate = sampleRate
+		}
+		m.samples += int64(samples)
+		if frameLength > len(data) {
+			m.skip = frameLength - len(data)
+			data = nil
+			break
+		}
+		data = data[frameLength:]
+	}
+	m.pending = append([]byte(nil), data...)
+}
+
+func (m *mp3Meter) ticks() int {
+	return int(m.measured + m.tickCount())
+}
+
+func (m *mp3Meter) tickCount() int64 {
+	if m.sampleRate == 0 {
+		return 0
+	}
+	return m.samples * ticksPerSecond / int64(m.sampleRate)
+}
+
+var (
+	mp3Bitrates = [2][16]int{
+		// MPEG-1 layer III
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		// MPEG-2 and MPEG-2.5 layer III
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	}
+	// mp3SampleRates is indexed by the version bits of the header.
+	mp3SampleRates = [4][3]int{
+		{11025, 12000, 8000},  // MPEG-2.5
+		{},                    // reserved
+		{22050, 24000, 16000}, // MPEG-2
+		{44100, 48000, 32000}, // MPEG-1
+	}
+)
+
+// parseMP3FrameHeader decodes the 4-byte header of an MPEG audio layer III frame and
+// returns the frame length in bytes, its number of samples and its sample rate.
+func parseMP3FrameHeader(h []byte) (frameLength, samples, sampleRate int, ok bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return 0, 0, 0, false
+	}
+	version := int(h[1]>>3) & 0x03
+	layer := int(h[1]>>1) & 0x03
+	bitrateIndex := int(h[2] >> 4)
+	sampleRateIndex := int(h[2]>>2) & 0x03
+	padding := int(h[2]>>1) & 0x01
+	if version == 1 || layer != 1 || sampleRateIndex == 3 {
+		return 0, 0, 0, false
+	}
+
+	mpeg1 := version == 3
+	table, coefficient, samples := 1, 72, 576
+	if mpeg1 {
+		table, coefficient, samples = 0, 144, 1152
+	}
+	bitrate := mp3Bitrates[table][bitrateIndex] * 1000
+	if bitrate == 0 {
+		return 0, 0, 0, false
+	}
+	sampleRate = mp3SampleRates[version][sampleRateIndex]
+	return coefficient*bitrate/sampleRate + padding, samples, sampleRate, true
+}
@@ -0,0 +1,58 @@
+package communicate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMP3MeterAcrossWrites(t *testing.T) {
+	// MPEG-2 layer III, 24kHz, 48kbps, mono: 144-byte frames of 576 samples (24ms)
+	frame := append([]byte{0xFF, 0xF3, 0x64, 0xC0}, make([]byte, 140)...)
+	audio := bytes.Repeat(frame, 50)
+
+	meter := newAudioMeter("audio-24khz-48kbitrate-mono-mp3")
+	for len(audio) > 0 {
+		n := min(97, len(audio)) // splits frames and headers
+		meter.write(audio[:n])
+		audio = audio[n:]
+	}
+	if got, want := meter.ticks(), 50*240_000; got != want {
+		t.Fatalf("ticks = %d, want %d", got, want)
+	}
+}
+
+func TestMP3FrameHeaderMPEG1(t *testing.T) {
+	// MPEG-1 layer III, 128kbps, 44.1kHz, padded
+	length, samples, rate, ok := parseMP3FrameHeader([]byte{0xFF, 0xFB, 0x92, 0x00})
+	if !ok || length != 418 || samples != 1152 || rate != 44100 {
+		t.Fatalf("got length %d, samples %d, rate %d, ok %v", length, samples, rate, ok)
+	}
+	if _, _, _, ok := parseMP3FrameHeader([]byte{0xFF, 0xFB, 0xF0, 0x00}); ok {
+		t.Fatal("expected a bad bitrate index to be rejected")
+	}
+}
+
+func TestPCMMeter(t *testing.T) {
+	meter := newAudioMeter("raw-16khz-16bit-mono-pcm")
+	meter.write(make([]byte, 16000))
+	meter.write(make([]byte, 16000))
+	if got := meter.ticks(); got != ticksPerSecond {
+		t.Fatalf("ticks = %d, want one second", got)
+	}
+
+	header := []byte("RIFF\x00\x00\x00\x00WAVEfmt ")
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = append(header, make([]byte, 16)...)
+	header = append(header, "data\x00\x00\x00\x00"...)
+	meter = newAudioMeter("riff-24khz-16bit-mono-pcm")
+	meter.write(append(header, make([]byte, 24000)...))
+	meter.write(make([]byte, 24000))
+	if got := meter.ticks(); got != ticksPerSecond {
+		t.Fatalf("riff ticks = %d, want one second", got)
+	}
+
+	if newAudioMeter("ogg-24khz-16bit-mono-opus") != nil {
+		t.Fatal("expected no meter for opus")
+	}
+}
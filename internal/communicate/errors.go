@@ -0,0 +1,155 @@
+package communicate
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrNoAudioReceived is matched by every NoAudioError through errors.Is.
+var ErrNoAudioReceived = errors.New("no audio received")
+
+// Each timeout is reported as a TimeoutError matching one of these through errors.Is.
+var (
+	ErrDialTimeout      = errors.New("dial timeout")
+	ErrFirstByteTimeout = errors.New("first byte timeout")
+	ErrIdleTimeout      = errors.New("idle timeout")
+)
+
+// HandshakeError is returned when a websocket connection to the service cannot be
+// established, either because the server rejected the upgrade or because the
+// connection could not be opened at all.
+type HandshakeError struct {
+	// StatusCode is the HTTP status of a rejected upgrade, or 0 if no response was received.
+	StatusCode   int
+	ConnectionID string
+	Err          error
+}
+
+func (e *HandshakeError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("websocket handshake failed with status %d %s (connection %s): %v",
+			e.StatusCode, http.StatusText(e.StatusCode), e.ConnectionID, e.Err)
+	}
+	return fmt.Sprintf("websocket handshake failed (connection %s): %v", e.ConnectionID, e.Err)
+}
+
+func (e *HandshakeError) Unwrap() error { return e.Err }
+
+// ProtocolError is returned when the service sends a message that does not follow
+// the expected protocol, such as an unknown path or malformed metadata.
+type ProtocolError struct {
+	Message      string
+	ConnectionID string
+	RequestID    string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("protocol error (connection %s, request %s): %s", e.ConnectionID, e.RequestID, e.Message)
+}
+
+// CloseError is returned when an established connection fails or is closed before
+// a turn completes. Code is the websocket close code; failures without a close frame
+// are reported as websocket.CloseAbnormalClosure (1006).
+type CloseError struct {
+	Code         int
+	ConnectionID string
+	RequestID    string
+	Err          error
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed with code %d (connection %s, request %s): %v", e.Code, e.ConnectionID, e.RequestID, e.Err)
+}
+
+func (e *CloseError) Unwrap() error { return e.Err }
+
+// NoAudioError is returned when a turn ends without producing any audio.
+type NoAudioError struct {
+	Reason       string
+	ConnectionID string
+	RequestID    string
+}
+
+func (e *NoAudioError) Error() string {
+	return fmt.Sprintf("%v (connection %s, request %s): %s", ErrNoAudioReceived, e.ConnectionID, e.RequestID, e.Reason)
+}
+
+func (e *NoAudioError) Is(target error) bool { return target == ErrNoAudioReceived }
+
+// ResumeError is returned when a chunk is retried after some of its audio was delivered
+// and the new attempt does not repeat that audio byte for byte, so that the rest of it
+// cannot be appended without corrupting the output. Delivered is the number of bytes of
+// the chunk already written.
+type ResumeError struct {
+	Delivered    int
+	ConnectionID string
+	RequestID    string
+}
+
+func (e *ResumeError) Error() string {
+	return fmt.Sprintf("retried chunk does not repeat the %d bytes of audio already delivered (connection %s, request %s)", e.Delivered, e.ConnectionID, e.RequestID)
+}
+
+// WriterError is returned when the caller's io.Writer fails to accept audio.
+type WriterError struct {
+	ConnectionID string
+	RequestID    string
+	Err          error
+}
+
+func (e *WriterError) Error() string {
+	return fmt.Sprintf("write audio payload (connection %s, request %s): %v", e.ConnectionID, e.RequestID, e.Err)
+}
+
+func (e *WriterError) Unwrap() error { return e.Err }
+
+// TimeoutError is returned when a dial, the first audio of a turn or the next message
+// of a turn takes longer than the configured timeout. Err is ErrDialTimeout,
+// ErrFirstByteTimeout or ErrIdleTimeout.
+type TimeoutError struct {
+	Err          error
+	Timeout      time.Duration
+	ConnectionID string
+	RequestID    string
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%v after %v (connection %s, request %s)", e.Err, e.Timeout, e.ConnectionID, e.RequestID)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// newCloseError wraps a read or write failure on an established connection. Timeouts
+// are returned unchanged, as the connection was closed by the client.
+func (c *Communicate) newCloseError(err error) error {
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr
+	}
+	code := websocket.CloseAbnormalClosure
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		code = closeErr.Code
+	}
+	return &CloseError{Code: code, ConnectionID: c.connectionID, RequestID: c.requestID, Err: err}
+}
+
+func (c *Communicate) newTimeoutError(err error, timeout time.Duration) *TimeoutError {
+	return &TimeoutError{Err: err, Timeout: timeout, ConnectionID: c.connectionID, RequestID: c.requestID}
+}
+
+func (c *Communicate) newProtocolError(message string) *ProtocolError {
+	return &ProtocolError{Message: message, ConnectionID: c.connectionID, RequestID: c.requestID}
+}
+
+func (c *Communicate) newResumeError(delivered int) *ResumeError {
+	return &ResumeError{Delivered: delivered, ConnectionID: c.connectionID, RequestID: c.requestID}
+}
+
+func (c *Communicate) newNoAudioError(reason string) *NoAudioError {
+	return &NoAudioError{Reason: reason, ConnectionID: c.connectionID, RequestID: c.requestID}
+}
@@ -0,0 +1,77 @@
+package communicate
+
+import "context"
+
+// EventType identifies the kind of an Event.
+type EventType int
+
+const (
+	// EventAudio carries a piece of audio in Audio.
+	EventAudio EventType = iota
+	// EventBoundary carries a word or sentence boundary in Boundary.
+	EventBoundary
+	// EventTurnStart is reported when the service starts a turn. A retried chunk
+	// reports one turn start for every attempt.
+	EventTurnStart
+	// EventTurnEnd is reported when the service finished a turn.
+	EventTurnEnd
+	// EventError carries the error in Err. It is always the last event of a stream.
+	EventError
+)
+
+// Event is one item produced while synthesizing. Index is the chunk the event belongs to;
+// ConnectionID and RequestID identify the connection and turn that produced it.
+type Event struct {
+	Type         EventType
+	Index        int
+	ConnectionID string
+	RequestID    string
+
+	Audio    []byte
+	Boundary Boundary
+	Err      error
+}
+
+// eventSink receives the events produced for a chunk. send reports whether the event
+// was accepted; false means the consumer went away and synthesis should stop.
+type eventSink interface {
+	send(e Event) bool
+}
+
+// channelSink delivers events to the consumer until ctx is done, so that a consumer
+// that stops reading never leaves the producing goroutine blocked.
+type channelSink struct {
+	ctx context.Context
+	ch  chan Event
+}
+
+func (s channelSink) send(e Event) bool {
+	select {
+	case s.ch <- e:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// shiftedSink moves the boundaries of one chunk, whose offsets are relative to the
+// start of that chunk, onto the timeline of the whole input.
+type shiftedSink struct {
+	eventSink
+	shift int
+}
+
+func (s shiftedSink) send(e Event) bool {
+	if e.Type == EventBoundary {
+		e.Boundary.Offset += s.shift
+	}
+	return s.eventSink.send(e)
+}
+
+func (c *Communicate) newEvent(eventType EventType, idx int) Event {
+	return Event{Type: eventType, Index: idx, ConnectionID: c.connectionID, RequestID: c.requestID}
+}
+
+func errorEvent(err error) Event {
+	return Event{Type: EventError, Err: err}
+}
@@ -2,111 +2,156 @@ package communicate
 
 import (
 	"encoding/binary"
-	"github.com/gorilla/websocket"
+	"hash/fnv"
 	"log"
+	"strings"
+
+	"github.com/lib-x/edgetts/internal/transport"
 )
 
-func (c *Communicate) handleWebSocketMessage(msgType int, message []byte, output chan map[string]interface{}, idx int, downloadAudio *bool, audioWasReceived *bool) bool {
+func (c *Communicate) handleWebSocketMessage(msgType int, message []byte, output eventSink, idx int, state *turnState) bool {
 	switch msgType {
-	case websocket.TextMessage:
-		return c.handleTextMessage(message, output, idx, downloadAudio)
-	case websocket.BinaryMessage:
-		return c.handleBinaryMessage(message, output, idx, downloadAudio, audioWasReceived)
+	case transport.TextMessage:
+		return c.handleTextMessage(message, output, idx, state)
+	case transport.BinaryMessage:
+		return c.handleBinaryMessage(message, output, idx, state)
 	default:
 		log.Printf("Received unknown message type: %d", msgType)
 		return true
 	}
 }
 
-func (c *Communicate) handleTextMessage(message []byte, output chan map[string]interface{}, idx int, downloadAudio *bool) bool {
+func (c *Communicate) handleTextMessage(message []byte, output eventSink, idx int, state *turnState) bool {
 	parameters, data := processWebsocketTextMessage(message)
+	if !c.isCurrentRequest(parameters) {
+		// late message from an earlier turn on a reused connection
+		return true
+	}
 	path := parameters["Path"]
 
 	switch path {
 	case "turn.start":
-		*downloadAudio = true
+		state.started = true
+		output.send(c.newEvent(EventTurnStart, idx))
 	case "turn.end":
-		output <- map[string]interface{}{
-			"end": "",
+		if state.audioSeen < state.progress.audioBytes {
+			// a retry that ends before repeating the audio already delivered
+			output.send(errorEvent(c.newResumeError(state.progress.audioBytes)))
+			return false
 		}
-		*downloadAudio = false
+		output.send(c.newEvent(EventTurnEnd, idx))
+		state.started = false
+		state.ended = true
 		return false // End of audio data
 
 	case "audio.metadata":
 		meta, err := metaDataContextFrom(data)
 		if err != nil {
-			output <- map[string]interface{}{
-				"error": unknownResponse{Message: err.Error()},
-			}
+			output.send(errorEvent(c.newProtocolError(err.Error())))
 			return false
 		}
 
 		for _, metaObj := range meta.Metadata {
 			metaType := metaObj.Type
-			if idx != c.prevIdx {
-				c.shiftTime = sumWithMap(idx, c.finalUtterance)
-				c.prevIdx = idx
-			}
 			switch metaType {
-			case "WordBoundary":
-				c.finalUtterance[idx] = metaObj.Data.Offset + metaObj.Data.Duration + wordBoundaryOffset
-				output <- map[string]interface{}{
-					"type":     metaType,
-					"offset":   metaObj.Data.Offset + c.shiftTime,
-					"duration": metaObj.Data.Duration,
-					"text":     metaObj.Data.Text,
+			case "WordBoundary", "SentenceBoundary":
+				state.boundariesSeen++
+				if state.boundariesSeen <= state.progress.boundaries {
+					// already delivered by an earlier attempt at this chunk
+					continue
+				}
+				state.progress.boundaries = state.boundariesSeen
+				// sentence boundaries span the words they contain, so keep the furthest end seen
+				// for this chunk as a fallback for its duration.
+				if end := metaObj.Data.Offset + metaObj.Data.Duration + wordBoundaryOffset; end > state.progress.end {
+					state.progress.end = end
 				}
+				event := c.newEvent(EventBoundary, idx)
+				event.Boundary = Boundary{
+					Type:     metaType,
+					Offset:   metaObj.Data.Offset,
+					Duration: metaObj.Data.Duration,
+					Text:     metaObj.Data.Text.Text,
+				}
+				output.send(event)
 			case "SessionEnd":
 				// do nothing
 			default:
-				output <- map[string]interface{}{
-					"error": unknownResponse{Message: "Unknown metadata type: " + metaType},
-				}
+				output.send(errorEvent(c.newProtocolError("unknown metadata type: " + metaType)))
 				return false
 			}
 		}
 	case "response":
 		// do nothing
 	default:
-		output <- map[string]interface{}{
-			"error": unknownResponse{Message: "The response from the service is not recognized.\n" + string(message)},
-		}
+		output.send(errorEvent(c.newProtocolError("the response from the service is not recognized:\n" + string(message))))
 		return false
 	}
 	return true
 }
 
-func (c *Communicate) handleBinaryMessage(message []byte, output chan map[string]interface{}, idx int, downloadAudio *bool, audioWasReceived *bool) bool {
-	if !*downloadAudio {
-		output <- map[string]interface{}{
-			"error": unknownResponse{"We received a binary message, but we are not expecting one."},
-		}
+func (c *Communicate) handleBinaryMessage(message []byte, output eventSink, idx int, state *turnState) bool {
+	if !state.started {
+		output.send(errorEvent(c.newProtocolError("received a binary message before turn.start")))
 		return false
 	}
 
 	if len(message) < binaryMessageHeaderSize {
-		output <- map[string]interface{}{
-			"error": unknownResponse{"We received a binary message, but it is missing the header length."},
-		}
+		output.send(errorEvent(c.newProtocolError("received a binary message without a header length")))
 		return false
 	}
 
 	headerLength := int(binary.BigEndian.Uint16(message[:2]))
 	if len(message) < headerLength+2 {
-		output <- map[string]interface{}{
-			"error": unknownResponse{"We received a binary message, but it is missing the audio data."},
-		}
+		output.send(errorEvent(c.newProtocolError("received a binary message without audio data")))
 		return false
 	}
 
+	if !c.isCurrentRequest(parseHeaderLines(message[2 : headerLength+2])) {
+		return true
+	}
+
 	audioBinaryData := message[headerLength+2:]
-	output <- map[string]interface{}{
-		"type": "audio",
-		"data": audioData{
-			Data:  audioBinaryData,
-			Index: idx,
-		},
+	state.audioReceived = true
+	if idx > 0 && state.audioSeen == 0 && strings.HasPrefix(c.opt.OutputFormat, "riff-") {
+		// every turn starts with its own WAV header; keep only the first chunk's
+		audioBinaryData = audioBinaryData[min(riffHeaderLength(audioBinaryData), len(audioBinaryData)):]
 	}
-	*audioWasReceived = true
+
+	// skip the bytes an earlier attempt at this chunk already delivered, provided they
+	// are repeated exactly: resuming other audio would splice two different streams
+	start := state.audioSeen
+	state.audioSeen += len(audioBinaryData)
+	if delivered := state.progress.audioBytes; start < delivered {
+		overlap := min(len(audioBinaryData), delivered-start)
+		if state.replay == nil {
+			state.replay = fnv.New64a()
+		}
+		state.replay.Write(audioBinaryData[:overlap])
+		if start+overlap == delivered && state.replay.Sum64() != state.progress.digest.Sum64() {
+			output.send(errorEvent(c.newResumeError(delivered)))
+			return false
+		}
+		audioBinaryData = audioBinaryData[overlap:]
+		if len(audioBinaryData) == 0 {
+			return true
+		}
+	}
+	state.progress.audioBytes = state.audioSeen
+	state.progress.digest.Write(audioBinaryData)
+	if state.progress.meter != nil {
+		state.progress.meter.write(audioBinaryData)
+	}
+
+	event := c.newEvent(EventAudio, idx)
+	event.Audio = audioBinaryData
+	output.send(event)
 	return true
 }
+
+// isCurrentRequest reports whether a message belongs to the turn being synthesized.
+// Messages without an X-RequestId header are accepted.
+func (c *Communicate) isCurrentRequest(headers map[string]string) bool {
+	id, ok := headers["X-RequestId"]
+	return !ok || c.requestID == "" || id == c.requestID
+}
@@ -0,0 +1,251 @@
+package communicate
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib-x/edgetts/internal/communicateOption"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+var (
+	// ErrInvalidProxy is matched by the error reported for a malformed proxy.
+	ErrInvalidProxy = errors.New("invalid proxy")
+	// ErrHTTPClientTransport is reported when the dialer, proxy or TLS options are set
+	// along with an HTTPClient whose transport is not an *http.Transport.
+	ErrHTTPClientTransport = errors.New("dialer, proxy and TLS options need an HTTP client with an *http.Transport")
+)
+
+// dialFunc opens a network connection, like net.Dialer.DialContext.
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// ProxyFunc returns the proxy selector of the proxy options, or nil if none is set. A
+// malformed proxy, including one taken from the environment, is reported as an error
+// matching ErrInvalidProxy, with any credentials redacted.
+func ProxyFunc(opt *communicateOption.CommunicateOption) (func(*http.Request) (*url.URL, error), error) {
+	switch {
+	case opt.ProxyURL != "":
+		return fixedProxy(opt.ProxyURL)
+	case opt.Socket5Proxy != "":
+		u := &url.URL{Scheme: "socks5", Host: opt.Socket5Proxy}
+		if opt.Socket5ProxyUser != "" || opt.Socket5ProxyPass != "" {
+			u.User = url.UserPassword(opt.Socket5ProxyUser, opt.Socket5ProxyPass)
+		}
+		return fixedProxy(u.String())
+	case opt.HttpProxy != "":
+		return fixedProxy(withDefaultScheme(opt.HttpProxy))
+	case opt.ProxyEnvironment != nil:
+		return environmentProxy(opt.ProxyEnvironment)
+	default:
+		return nil, nil
+	}
+}
+
+func fixedProxy(raw string) (func(*http.Request) (*url.URL, error), error) {
+	proxyURL, err := parseProxy(raw)
+	if err != nil {
+		return nil, err
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// environmentProxy validates the proxies of config, which would otherwise only fail once
+// they are selected.
+func environmentProxy(config *httpproxy.Config) (func(*http.Request) (*url.URL, error), error) {
+	for _, raw := range []string{config.HTTPProxy, config.HTTPSProxy} {
+		if raw == "" {
+			continue
+		}
+		if _, err := parseProxy(withDefaultScheme(raw)); err != nil {
+			return nil, err
+		}
+	}
+	proxyFunc := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// withDefaultScheme treats a bare host:port as an http proxy, as HTTP_PROXY does.
+func withDefaultScheme(raw string) string {
+	if strings.Contains(raw, "://") {
+		return raw
+	}
+	return "http://" + raw
+}
+
+// parseProxy parses an http, https, socks5 or socks5h proxy URL.
+func parseProxy(raw string) (*url.URL, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidProxy, redactURL(raw), reason)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// the error of url.Parse quotes the URL with its credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, invalid(urlErr.Err.Error())
+		}
+		return nil, invalid("malformed URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, invalid(fmt.Sprintf("unsupported scheme %q, want http, https, socks5 or socks5h", u.Scheme))
+	}
+	if u.Hostname() == "" {
+		return nil, invalid("missing host")
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return nil, invalid("unexpected path or query")
+	}
+	return u, nil
+}
+
+// redactURL replaces the credentials of a URL, which may not parse, with xxxxx.
+func redactURL(raw string) string {
+	scheme, rest := "", raw
+	if i := strings.Index(raw, "://"); i >= 0 {
+		scheme, rest = raw[:i+3], raw[i+3:]
+	}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		return scheme + "xxxxx" + rest[i:]
+	}
+	return raw
+}
+
+// socksDialContext returns a dial function through the SOCKS5 proxy at proxyURL,
+// reached through forward, or directly if forward is nil. Host names are resolved by
+// the proxy for both the socks5 and socks5h schemes.
+func socksDialContext(proxyURL *url.URL, forward dialFunc) dialFunc {
+	if forward == nil {
+		forward = (&net.Dialer{}).DialContext
+	}
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+	address := proxyURL.Host
+	if proxyURL.Port() == "" {
+		address = net.JoinHostPort(proxyURL.Hostname(), "1080")
+	}
+	// SOCKS5 only fails for a nil forward dialer, and the returned dialer honours ctx
+	// through forward.
+	dialer, _ := proxy.SOCKS5("tcp", address, auth, forwardDialer(forward))
+	return dialer.(proxy.ContextDialer).DialContext
+}
+
+// forwardDialer adapts a dialFunc to the dialer interfaces of golang.org/x/net/proxy.
+type forwardDialer dialFunc
+
+func (f forwardDialer) Dial(network, address string) (net.Conn, error) {
+	return f(context.Background(), network, address)
+}
+
+func (f forwardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
+// proxyTLSConfig returns the TLS configuration for an https proxy at host. It keeps
+// the roots, client certificates and InsecureSkipVerify of base, the configuration for
+// the service, but not its server name, protocols or custom verification, which are
+// meant for the service only.
+func proxyTLSConfig(base *tls.Config, host string) *tls.Config {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+	config.ServerName = host
+	config.NextProtos = nil
+	config.VerifyPeerCertificate = nil
+	config.VerifyConnection = nil
+	return config
+}
+
+// connectDialContext returns a dial function tunnelling through the HTTP proxy at
+// proxyURL with CONNECT, reached through forward, or directly if forward is nil. An
+// https proxy is spoken to over TLS with the configuration proxyTLSConfig derives from
+// tlsConfig.
+func connectDialContext(proxyURL *url.URL, forward dialFunc, tlsConfig *tls.Config) dialFunc {
+	if forward == nil {
+		forward = (&net.Dialer{}).DialContext
+	}
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	proxyTLS := proxyTLSConfig(tlsConfig, proxyURL.Hostname())
+	header := make(http.Header)
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := forward(ctx, network, proxyAddress)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL.Scheme == "https" {
+			tlsConn := tls.Client(conn, proxyTLS.Clone())
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+		// unblock the exchange below when ctx ends
+		stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+		defer stop()
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: address},
+			Host:   address,
+			Header: header,
+		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, contextError(ctx, err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			conn.Close()
+			return nil, contextError(ctx, err)
+		}
+		// the body of a successful CONNECT is the tunnel, and the service does not
+		// speak first, so nothing is lost with the reader
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s: CONNECT %s: %s", redactURL(proxyURL.String()), address, resp.Status)
+		}
+		if !stop() {
+			conn.Close()
+			return nil, ctx.Err()
+		}
+		return conn, nil
+	}
+}
+
+// contextError returns the error of ctx if it ended, which caused err, or else err.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
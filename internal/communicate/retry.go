@@ -0,0 +1,102 @@
+package communicate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxRetryBackoff caps the delay between two attempts.
+const maxRetryBackoff = 30 * time.Second
+
+// backoff reports whether a failed attempt should be retried. If so it increments
+// *retries and waits, doubling the configured backoff after every retry.
+func (c *Communicate) backoff(ctx context.Context, err error, retries *int) bool {
+	if *retries >= c.opt.MaxRetries || !isRetryable(err) {
+		return false
+	}
+	delay := retryDelay(c.opt.RetryBackoff, *retries)
+	*retries++
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// retryDelay returns base doubled retries times, capped at maxRetryBackoff.
+func retryDelay(base time.Duration, retries int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	for ; retries > 0 && base < maxRetryBackoff; retries-- {
+		base *= 2
+	}
+	return min(base, maxRetryBackoff)
+}
+
+// isRetryable reports whether err is a transient network or service failure that is
+// worth retrying on a new connection. Rejected handshakes are only retried for 429
+// and 5xx statuses; context cancellation is never retried, timeouts always are.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return true
+	}
+
+	var handshakeErr *HandshakeError
+	if errors.As(err, &handshakeErr) && handshakeErr.StatusCode != 0 {
+		return handshakeErr.StatusCode == http.StatusTooManyRequests || handshakeErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case websocket.CloseNormalClosure,
+			websocket.CloseGoingAway,
+			websocket.CloseAbnormalClosure,
+			websocket.CloseInternalServerErr,
+			websocket.CloseServiceRestart,
+			websocket.CloseTryAgainLater:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
@@ -0,0 +1,58 @@
+package communicate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"handshake 503", &HandshakeError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"handshake 429", &HandshakeError{StatusCode: http.StatusTooManyRequests}, true},
+		{"handshake 403", &HandshakeError{StatusCode: http.StatusForbidden}, false},
+		{"abnormal close", &websocket.CloseError{Code: websocket.CloseAbnormalClosure}, true},
+		{"policy close", &websocket.CloseError{Code: websocket.ClosePolicyViolation}, false},
+		{"unexpected eof", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"reset", &CloseError{Code: websocket.CloseAbnormalClosure, Err: syscall.ECONNRESET}, true},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tc := range cases {
+		if got := isRetryable(tc.err); got != tc.want {
+			t.Errorf("%s: isRetryable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		base    time.Duration
+		retries int
+		want    time.Duration
+	}{
+		{0, 3, 0},
+		{time.Second, 0, time.Second},
+		{time.Second, 3, 8 * time.Second},
+		{time.Second, 10, maxRetryBackoff},
+		{time.Second, 100, maxRetryBackoff},
+		{time.Minute, 0, maxRetryBackoff},
+	}
+	for _, tc := range cases {
+		if got := retryDelay(tc.base, tc.retries); got != tc.want {
+			t.Errorf("retryDelay(%v, %d) = %v, want %v", tc.base, tc.retries, got, tc.want)
+		}
+	}
+}
@@ -81,5 +81,6 @@ type Prosody struct {
 	//	x-loud
 	//	default
 	Volume string `xml:"volume,attr"`
-	Text   string `xml:",chardata"`
+	// Text is inserted as is; it must already be escaped and may contain markup.
+	Text string `xml:",innerxml"`
 }
@@ -0,0 +1,161 @@
+package communicate
+
+import (
+	"github.com/lib-x/edgetts/ssml"
+)
+
+// splitSSML splits an SSML document into documents of at most maxBytes bytes. Each
+// piece keeps the speak, voice, prosody and other elements enclosing its content.
+// Pieces end between the children of an element, such as <p> and <s> elements, so
+// sentences and paragraphs are only split when they alone exceed maxBytes; text is then
+// split like a text input. A document that fits, or cannot be parsed, is returned as
+// is, and elements without children that exceed maxBytes on their own are kept whole.
+func splitSSML(document string, maxBytes int) [][]byte {
+	if len(document) <= maxBytes {
+		return [][]byte{[]byte(document)}
+	}
+	speak, err := ssml.ParseString(document)
+	if err != nil {
+		// let the service report what is wrong with it
+		return [][]byte{[]byte(document)}
+	}
+
+	var payloads [][]byte
+	for _, piece := range splitNode(speak, maxBytes) {
+		payloads = append(payloads, []byte(ssml.Render(piece)))
+	}
+	return payloads
+}
+
+// splitNode returns copies of n, each holding consecutive children of n, that render to
+// at most maxBytes bytes when possible.
+func splitNode(n ssml.Node, maxBytes int) []ssml.Node {
+	if len(ssml.Render(n)) <= maxBytes {
+		return []ssml.Node{n}
+	}
+	if text, ok := n.(ssml.Text); ok {
+		return splitSSMLText(text, maxBytes)
+	}
+	children, ok := childrenOf(n)
+	if !ok || len(children) == 0 {
+		return []ssml.Node{n}
+	}
+
+	// an empty text child keeps the end tag in the rendering
+	budget := maxBytes - len(ssml.Render(withChildren(n, []ssml.Node{ssml.Text("")})))
+	var (
+		pieces  []ssml.Node
+		current []ssml.Node
+		size    int
+	)
+	flush := func() {
+		if len(current) > 0 {
+			pieces = append(pieces, withChildren(n, current))
+			current, size = nil, 0
+		}
+	}
+	for len(children) > 0 {
+		child := children[0]
+		children = children[1:]
+		length := len(ssml.Render(child))
+		if size+length <= budget {
+			current = append(current, child)
+			size += length
+			continue
+		}
+		flush()
+		if length <= budget {
+			current, size = []ssml.Node{child}, length
+			continue
+		}
+		// pack the parts of an oversized child like the remaining children
+		parts := splitNode(child, max(budget, 1))
+		if len(parts) == 1 {
+			pieces = append(pieces, withChildren(n, parts))
+			continue
+		}
+		children = append(parts, children...)
+	}
+	flush()
+	return pieces
+}
+
+// splitSSMLText splits text at paragraph, sentence or word boundaries so that each
+// piece renders to at most maxBytes bytes.
+func splitSSMLText(text ssml.Text, maxBytes int) []ssml.Node {
+	var pieces []ssml.Node
+	for _, chunk := range splitText(escape(string(text)), maxBytes) {
+		pieces = append(pieces, ssml.Text(unescape(string(chunk))))
+	}
+	return pieces
+}
+
+// childrenOf returns the children of an element that has them.
+func childrenOf(n ssml.Node) ([]ssml.Node, bool) {
+	switch n := n.(type) {
+	case *ssml.Speak:
+		return n.Children, true
+	case *ssml.Voice:
+		return n.Children, true
+	case *ssml.Lang:
+		return n.Children, true
+	case *ssml.Prosody:
+		return n.Children, true
+	case *ssml.Emphasis:
+		return n.Children, true
+	case *ssml.Paragraph:
+		return n.Children, true
+	case *ssml.Sentence:
+		return n.Children, true
+	case *ssml.ExpressAs:
+		return n.Children, true
+	case *ssml.Element:
+		return n.Children, true
+	default:
+		return nil, false
+	}
+}
+
+// withChildren returns a copy of the element n with children instead of its own.
+func withChildren(n ssml.Node, children []ssml.Node) ssml.Node {
+	switch n := n.(type) {
+	case *ssml.Speak:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.Voice:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.Lang:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.Prosody:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.Emphasis:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.Paragraph:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.Sentence:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.ExpressAs:
+		c := *n
+		c.Children = children
+		return &c
+	case *ssml.Element:
+		c := *n
+		c.Children = children
+		return &c
+	default:
+		return n
+	}
+}
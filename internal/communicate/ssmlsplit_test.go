@@ -0,0 +1,101 @@
+package communicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lib-x/edgetts/ssml"
+)
+
+func TestSplitSSMLKeepsContext(t *testing.T) {
+	var sentences strings.Builder
+	for range 20 {
+		sentences.WriteString("<s>The quick brown fox &amp; the lazy dog.</s>")
+	}
+	document := `<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="en-US">` +
+		`<voice name="en-US-GuyNeural"><prosody rate="+10%"><p>` + sentences.String() + `</p>` +
+		`<p>` + strings.Repeat("word ", 100) + `</p></prosody></voice></speak>`
+
+	const maxBytes = 400
+	payloads := splitSSML(document, maxBytes)
+	if len(payloads) < 3 {
+		t.Fatalf("pieces = %d, want the document split", len(payloads))
+	}
+	var text strings.Builder
+	for _, payload := range payloads {
+		if len(payload) > maxBytes {
+			t.Fatalf("piece of %d bytes exceeds %d: %s", len(payload), maxBytes, payload)
+		}
+		piece, err := ssml.ParseString(string(payload))
+		if err != nil {
+			t.Fatalf("piece does not parse: %v\n%s", err, payload)
+		}
+		voice, ok := piece.Children[0].(*ssml.Voice)
+		if !ok || voice.Name != "en-US-GuyNeural" {
+			t.Fatalf("piece lost its voice: %s", payload)
+		}
+		if prosody, ok := voice.Children[0].(*ssml.Prosody); !ok || prosody.Rate != "+10%" {
+			t.Fatalf("piece lost its prosody: %s", payload)
+		}
+		text.WriteString(speakable(piece))
+	}
+	want := strings.Repeat("The quick brown fox & the lazy dog.", 20) + strings.Repeat("word", 100)
+	if got := strings.Join(strings.Fields(text.String()), ""); got != strings.ReplaceAll(want, " ", "") {
+		t.Fatalf("pieces do not reassemble the text: %q", got)
+	}
+}
+
+func TestSplitSSMLKeepsAttributes(t *testing.T) {
+	document := `<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xmlns:acme="urn:acme" xml:lang="en-US">` +
+		`<voice name="en-US-GuyNeural" effect="eq_telecomhp8k"><acme:mark id="m"/>` +
+		strings.Repeat("<s>The quick brown fox.</s>", 20) + `</voice></speak>`
+
+	payloads := splitSSML(document, 300)
+	if len(payloads) < 2 {
+		t.Fatalf("pieces = %d, want the document split", len(payloads))
+	}
+	for i, payload := range payloads {
+		for _, want := range []string{`xmlns:acme="urn:acme"`, `<voice name="en-US-GuyNeural" effect="eq_telecomhp8k">`} {
+			if !strings.Contains(string(payload), want) {
+				t.Fatalf("piece %d lost %s: %s", i, want, payload)
+			}
+		}
+	}
+	if !strings.Contains(string(payloads[0]), `<acme:mark id="m"/>`) {
+		t.Fatalf("first piece lost the foreign element: %s", payloads[0])
+	}
+}
+
+func TestSplitSSMLKeepsSmallDocument(t *testing.T) {
+	document := `<speak version='1.0'><voice name='x'>hi</voice></speak>`
+	if payloads := splitSSML(document, 1000); len(payloads) != 1 || string(payloads[0]) != document {
+		t.Fatalf("splitSSML() = %q, want the document unchanged", payloads)
+	}
+}
+
+func TestSplitSSMLReturnsUnparsableDocument(t *testing.T) {
+	body := strings.Repeat("<s>The quick brown fox.</s>", 20)
+	for _, document := range []string{
+		`<speak xmlns="urn:not-ssml">` + body + `</speak>`,
+		`<speak version="1.0"><voice name="x">` + body,
+		`<voice name="x">` + body + `</voice>`,
+	} {
+		payloads := splitSSML(document, 100)
+		if len(payloads) != 1 || string(payloads[0]) != document {
+			t.Fatalf("splitSSML(%.40q) = %d pieces, want the document unchanged", document, len(payloads))
+		}
+	}
+}
+
+// speakable returns the character data of a parsed document.
+func speakable(n ssml.Node) string {
+	if text, ok := n.(ssml.Text); ok {
+		return string(text)
+	}
+	children, _ := childrenOf(n)
+	var b strings.Builder
+	for _, child := range children {
+		b.WriteString(speakable(child))
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package communicate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lib-x/edgetts/internal/transport"
+)
+
+// readWatchdog closes a connection when ctx is done or an armed timeout expires, which
+// unblocks a pending ReadMessage. cause then reports why the connection was closed.
+type readWatchdog struct {
+	conn   transport.Conn
+	stopFn func() bool
+
+	mu    sync.Mutex
+	timer *time.Timer
+	err   error
+}
+
+func newReadWatchdog(ctx context.Context, conn transport.Conn) *readWatchdog {
+	w := &readWatchdog{conn: conn}
+	w.stopFn = context.AfterFunc(ctx, func() { w.fire(ctx.Err()) })
+	return w
+}
+
+// arm replaces the pending timeout; the connection is closed with err if no other
+// arm or stop happens within d. A zero d disables the timeout.
+func (w *readWatchdog) arm(d time.Duration, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if d > 0 && w.err == nil {
+		w.timer = time.AfterFunc(d, func() { w.fire(err) })
+	}
+}
+
+func (w *readWatchdog) fire(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+		_ = w.conn.Close()
+	}
+}
+
+// cause returns why the watchdog closed the connection, or nil if it did not.
+func (w *readWatchdog) cause() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *readWatchdog) stop() {
+	w.stopFn()
+	w.arm(0, nil)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,74 @@
+package communicate
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+	"github.com/lib-x/edgetts/internal/transport"
+)
+
+// websocketTransport is the default transport, dialing the service with gorilla/websocket.
+type websocketTransport struct {
+	dialer websocket.Dialer
+	// proxy selects the proxy of an endpoint; nil, or a nil URL, means none.
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+func (t *websocketTransport) Dial(ctx context.Context, endpoint string, header http.Header) (transport.Conn, *http.Response, error) {
+	dialer, err := t.dialerFor(endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, resp, err := dialer.DialContext(ctx, endpoint, header)
+	if err != nil {
+		return nil, resp, err
+	}
+	return conn, resp, nil
+}
+
+// dialerFor returns the dialer for endpoint, going through its proxy if any. Proxies
+// are dialed here rather than by gorilla/websocket, which ignores the context and
+// supports neither https proxies nor the socks5h scheme.
+func (t *websocketTransport) dialerFor(endpoint string) (*websocket.Dialer, error) {
+	dialer := t.dialer
+	if t.proxy == nil {
+		return &dialer, nil
+	}
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// proxies are selected by the scheme of the HTTP upgrade request
+	switch target.Scheme {
+	case "ws":
+		target.Scheme = "http"
+	case "wss":
+		target.Scheme = "https"
+	}
+	proxyURL, err := t.proxy(&http.Request{URL: target, Host: target.Host})
+	if err != nil || proxyURL == nil {
+		return &dialer, err
+	}
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		dialer.NetDialContext = socksDialContext(proxyURL, dialer.NetDialContext)
+	default:
+		dialer.NetDialContext = connectDialContext(proxyURL, dialer.NetDialContext, dialer.TLSClientConfig)
+	}
+	return &dialer, nil
+}
+
+// newTransport returns the configured transport, or a websocket transport honouring
+// the dialer, proxy and TLS options.
+func (c *Communicate) newTransport() (transport.Transport, error) {
+	if c.opt.Transport != nil {
+		return c.opt.Transport, nil
+	}
+	t := &websocketTransport{}
+	if err := c.applyWebSocketProxyIfSet(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -1,7 +1,6 @@
 package communicate
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
@@ -13,8 +12,9 @@ import (
 )
 
 var (
-	uuidReplacer   = strings.NewReplacer("-", "")
-	escapeReplacer = strings.NewReplacer(">", "&gt;", "<", "&lt;")
+	uuidReplacer     = strings.NewReplacer("-", "")
+	escapeReplacer   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+	unescapeReplacer = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'")
 )
 
 func makeSsml(text string, pitch, voice string, rate string, volume string) string {
@@ -22,7 +22,7 @@ func makeSsml(text string, pitch, voice string, rate string, volume string) stri
 		XMLName: xml.Name{Local: "speak"},
 		Version: "1.0",
 		Xmlns:   "http://www.w3.org/2001/10/synthesis",
-		Lang:    "en-US",
+		Lang:    voiceLocale(voice),
 		Voice: []Voice{{
 			Name: voice,
 			Prosody: Prosody{
@@ -41,15 +41,30 @@ func makeSsml(text string, pitch, voice string, rate string, volume string) stri
 	return string(output)
 }
 
-func sumWithMap(idx int, m map[int]int) int {
-	if idx <= 0 || m == nil {
-		return 0
+// voiceLocale returns the locale of a voice name, everything before its last segment:
+// zh-CN for zh-CN-XiaoxiaoNeural and sr-Latn-RS for sr-Latn-RS-NicholasNeural. It
+// returns en-US if the name has no locale.
+func voiceLocale(voice string) string {
+	locale, _, ok := cutLast(voice, "-")
+	if !ok || !strings.Contains(locale, "-") {
+		return "en-US"
 	}
-	sumResult := 0
-	for i := 0; i < idx; i++ {
-		sumResult += m[i]
+	return locale
+}
+
+// isMultilingual reports whether voice is a multilingual voice, whose names end in
+// MultilingualNeural, such as en-US-AvaMultilingualNeural.
+func isMultilingual(voice string) bool {
+	_, name, _ := cutLast(voice, "-")
+	return strings.HasSuffix(name, "MultilingualNeural")
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
 	}
-	return sumResult
+	return s, "", false
 }
 
 func metaDataContextFrom(data []byte) (*metaDataContext, error) {
@@ -64,15 +79,25 @@ func metaDataContextFrom(data []byte) (*metaDataContext, error) {
 // processWebsocketTextMessage parses a websocket text message into headers and body.
 // It returns a map of headers and the message body as a byte slice.
 func processWebsocketTextMessage(data []byte) (headers map[string]string, body []byte) {
-	headers = make(map[string]string)
 	// Find the end of the headers section
 	headerEndIndex := bytes.Index(data, []byte("\r\n\r\n"))
 	if headerEndIndex == -1 {
 		// If there's no header separator, treat the entire message as body
-		return headers, data
+		return make(map[string]string), data
 	}
+	headers = parseHeaderLines(data[:headerEndIndex])
+	// The body starts after the headers
+	body = data[headerEndIndex+4:]
+
+	return headers, body
+}
+
+// parseHeaderLines parses CRLF separated "Key:Value" lines, such as the header block
+// that prefixes both text and binary websocket messages.
+func parseHeaderLines(data []byte) map[string]string {
+	headers := make(map[string]string)
 	// Split headers into individual lines
-	headerLines := bytes.Split(data[:headerEndIndex], []byte("\r\n"))
+	headerLines := bytes.Split(data, []byte("\r\n"))
 	// Parse each header line
 	for _, line := range headerLines {
 		parts := bytes.SplitN(line, []byte(":"), 2)
@@ -83,10 +108,7 @@ func processWebsocketTextMessage(data []byte) (headers map[string]string, body [
 		}
 		// Ignore malformed header lines
 	}
-	// The body starts after the headers
-	body = data[headerEndIndex+4:]
-
-	return headers, body
+	return headers
 }
 
 func removeIncompatibleCharacters(str string) string {
@@ -102,41 +124,10 @@ func generateConnectID() string {
 	return uuidReplacer.Replace(uuid.New().String())
 }
 
-// splitTextByByteLength splits the input text into chunks of a specified byte length.
-// The function ensures that the text is not split in the middle of a word. If a word exceeds the specified byte length,
-// the word is placed in the next chunk. The function returns a slice of byte slices, each representing a chunk of the original text.
-//
-// Parameters:
-// text: The input text to be split.
-// byteLength: The maximum byte length for each chunk of text.
-//
-// Returns:
-// A slice of byte slices, each representing a chunk of the original text.
-func splitTextByByteLength(text string, byteLength int) [][]byte {
-	var result [][]byte
-	scanner := bufio.NewScanner(strings.NewReader(text))
-	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		if atEOF && len(data) == 0 {
-			return 0, nil, nil
-		}
-		if len(data) > byteLength {
-			return byteLength, data[:byteLength], nil
-		}
-		return len(data), data, bufio.ErrFinalToken
-	})
-
-	for scanner.Scan() {
-		result = append(result, bytes.TrimSpace(scanner.Bytes()))
-	}
-
-	return result
-}
-
-func currentTimeInMST() string {
+func timestampInMST(now time.Time) string {
 	// Use time.FixedZone to represent a fixed timezone offset of 0 (UTC)
 	zone := time.FixedZone("UTC", 0)
-	now := time.Now().In(zone)
-	return now.Format("Mon Jan 02 2006 15:04:05 GMT-0700 (MST)")
+	return now.In(zone).Format("Mon Jan 02 2006 15:04:05 GMT-0700 (MST)")
 }
 
 func appendRequestContextToSsmlHeaders(requestID string, timestamp string, ssml string) string {
@@ -149,24 +140,22 @@ func appendRequestContextToSsmlHeaders(requestID string, timestamp string, ssml
 }
 
 func getMaxMessageSize(pitch, voice string, rate string, volume string) int {
+	return getMaxSSMLSize() - len(makeSsml("", pitch, voice, rate, volume))
+}
+
+// getMaxSSMLSize returns the largest SSML document that fits in one websocket message.
+func getMaxSSMLSize() int {
 	websocketMaxSize := 1 << 16
-	overheadPerMessage := len(appendRequestContextToSsmlHeaders(generateConnectID(), currentTimeInMST(), makeSsml("", pitch, voice, rate, volume))) + 50
+	overheadPerMessage := len(appendRequestContextToSsmlHeaders(generateConnectID(), timestampInMST(time.Now()), "")) + 50
 	return websocketMaxSize - overheadPerMessage
 }
 
+// escape escapes text for use as XML character data.
 func escape(data string) string {
-	// Must do ampersand first
-	entities := make(map[string]string)
-	data = escapeReplacer.Replace(data)
-	if entities != nil {
-		data = replaceWithDict(data, entities)
-	}
-	return data
+	return escapeReplacer.Replace(data)
 }
 
-func replaceWithDict(data string, entities map[string]string) string {
-	for key, value := range entities {
-		data = strings.ReplaceAll(data, key, value)
-	}
-	return data
+// unescape reverses escape.
+func unescape(data string) string {
+	return unescapeReplacer.Replace(data)
 }
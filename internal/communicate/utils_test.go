@@ -0,0 +1,26 @@
+package communicate
+
+import "testing"
+
+func TestVoiceLocale(t *testing.T) {
+	cases := []struct {
+		voice        string
+		locale       string
+		multilingual bool
+	}{
+		{"zh-CN-XiaoxiaoNeural", "zh-CN", false},
+		{"en-US-AvaMultilingualNeural", "en-US", true},
+		{"sr-Latn-RS-NicholasNeural", "sr-Latn-RS", false},
+		{"iu-Cans-CA-SiqiniqNeural", "iu-Cans-CA", false},
+		{"en-US-MultilingualHelperNeural", "en-US", false},
+		{"AriaNeural", "en-US", false},
+	}
+	for _, tc := range cases {
+		if got := voiceLocale(tc.voice); got != tc.locale {
+			t.Errorf("voiceLocale(%q) = %q, want %q", tc.voice, got, tc.locale)
+		}
+		if got := isMultilingual(tc.voice); got != tc.multilingual {
+			t.Errorf("isMultilingual(%q) = %v, want %v", tc.voice, got, tc.multilingual)
+		}
+	}
+}
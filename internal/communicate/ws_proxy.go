@@ -3,27 +3,135 @@ package communicate
 import (
 	"context"
 	"crypto/tls"
-	"github.com/gorilla/websocket"
-	"golang.org/x/net/proxy"
 	"net"
 	"net/http"
-	"net/url"
+	"time"
+
+	"github.com/lib-x/edgetts/internal/communicateOption"
 )
 
-func (c *Communicate) applyWebSocketProxyIfSet(dialer *websocket.Dialer) {
-	if c.opt.HttpProxy != "" {
-		proxyUrl, _ := url.Parse(c.opt.HttpProxy)
-		dialer.Proxy = http.ProxyURL(proxyUrl)
+// applyWebSocketProxyIfSet configures t with the HTTP client, dialer, proxy and TLS
+// options. Options set explicitly take precedence over the settings of the transport
+// of HTTPClient.
+func (c *Communicate) applyWebSocketProxyIfSet(t *websocketTransport) error {
+	proxyFunc, err := ProxyFunc(c.opt)
+	if err != nil {
+		return err
+	}
+	if base := httpTransportOf(c.opt.HTTPClient); base != nil {
+		t.proxy = base.Proxy
+		t.dialer.NetDialContext = base.DialContext
+		t.dialer.TLSClientConfig = base.TLSClientConfig
+	}
+	if c.opt.NetDialer != nil {
+		t.dialer.NetDialContext = c.opt.NetDialer.DialContext
+	}
+	if proxyFunc != nil {
+		t.proxy = proxyFunc
+	}
+	t.dialer.TLSClientConfig = tlsConfig(c.opt, t.dialer.TLSClientConfig)
+	if t.dialer.TLSClientConfig != nil {
+		// the websocket handshake is HTTP/1.1 only
+		t.dialer.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	}
+	return nil
+}
+
+// NewHTTPClient returns an HTTP client for the requests made besides synthesis, such as
+// listing voices, honouring the same dialer, proxy and TLS options as the websocket,
+// with DialTimeout bounding the connection and FirstByteTimeout the wait for the
+// response headers. HTTPClient is returned as is when none of the dialer, proxy and TLS
+// options is set; otherwise they are applied to a copy of its *http.Transport, and
+// ErrHTTPClientTransport is returned if it has another http.RoundTripper. A malformed
+// proxy is reported as for ProxyFunc.
+func NewHTTPClient(opt *communicateOption.CommunicateOption) (*http.Client, error) {
+	proxyFunc, err := ProxyFunc(opt)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opt.HTTPClient != nil {
+		if proxyFunc == nil && opt.NetDialer == nil && opt.TLSConfig == nil && !opt.IgnoreSSL {
+			return opt.HTTPClient, nil
+		}
+		copied := *opt.HTTPClient
+		client = &copied
+		switch t := opt.HTTPClient.Transport.(type) {
+		case nil:
+			// as for the websocket, environment proxies are opt-in
+			transport.Proxy = nil
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			return nil, ErrHTTPClientTransport
+		}
+	}
+
+	dial := transport.DialContext
+	if opt.NetDialer != nil {
+		dial = opt.NetDialer.DialContext
+	}
+	if dial != nil {
+		transport.DialContext = withDialTimeout(dial, opt.DialTimeout)
+	}
+	if proxyFunc != nil {
+		// net/http dials socks5 proxies itself, through DialContext
+		transport.Proxy = proxyFunc
+	}
+	if opt.DialTimeout > 0 {
+		transport.TLSHandshakeTimeout = opt.DialTimeout
+	}
+	if opt.FirstByteTimeout > 0 {
+		transport.ResponseHeaderTimeout = opt.FirstByteTimeout
+	}
+	transport.TLSClientConfig = tlsConfig(opt, transport.TLSClientConfig)
+	client.Transport = transport
+	return client, nil
+}
+
+// withDialTimeout bounds every dial of dial by timeout, if positive.
+func withDialTimeout(dial func(ctx context.Context, network, address string) (net.Conn, error), timeout time.Duration) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if timeout <= 0 {
+		return dial
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return dial(ctx, network, address)
+	}
+}
+
+// httpTransportOf returns the *http.Transport of client, or nil if client is nil or
+// uses another http.RoundTripper. A nil Transport has no settings to take: taking those
+// of http.DefaultTransport would enable environment proxies, which are opt-in.
+func httpTransportOf(client *http.Client) *http.Transport {
+	if client == nil {
+		return nil
+	}
+	t, _ := client.Transport.(*http.Transport)
+	return t
+}
+
+// tlsConfig returns a copy of TLSConfig, or else of base, with certificate verification
+// disabled when IgnoreSSL is set, or nil if there is neither. Every caller gets its own
+// copy, since net/http adds "h2" to the NextProtos of the configuration of a transport
+// and the websocket dialer must not negotiate it.
+func tlsConfig(opt *communicateOption.CommunicateOption, base *tls.Config) *tls.Config {
+	config := base
+	if opt.TLSConfig != nil {
+		config = opt.TLSConfig
 	}
-	if c.opt.Socket5Proxy != "" {
-		auth := &proxy.Auth{User: c.opt.Socket5ProxyUser, Password: c.opt.Socket5ProxyPass}
-		socket5ProxyDialer, _ := proxy.SOCKS5("tcp", c.opt.Socket5Proxy, auth, proxy.Direct)
-		dialContext := func(ctx context.Context, network, address string) (net.Conn, error) {
-			return socket5ProxyDialer.Dial(network, address)
+	if config == nil {
+		if !opt.IgnoreSSL {
+			return nil
 		}
-		dialer.NetDialContext = dialContext
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
 	}
-	if c.opt.IgnoreSSL {
-		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	if opt.IgnoreSSL {
+		config.InsecureSkipVerify = true
 	}
+	return config
 }
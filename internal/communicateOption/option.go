@@ -1,10 +1,16 @@
 package communicateOption
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/lib-x/edgetts/internal/businessConsts"
+	"github.com/lib-x/edgetts/internal/transport"
+	"golang.org/x/net/http/httpproxy"
 )
 
 type CommunicateOption struct {
@@ -18,6 +24,48 @@ type CommunicateOption struct {
 	Socket5ProxyUser string
 	Socket5ProxyPass string
 	IgnoreSSL        bool
+	OutputFormat     string
+	// ProxyURL is an http, https, socks5 or socks5h proxy URL, with optional credentials.
+	ProxyURL string
+	// ProxyEnvironment selects proxies from HTTP_PROXY, HTTPS_PROXY and NO_PROXY, as read
+	// from the environment when the option was created.
+	ProxyEnvironment *httpproxy.Config
+	// HTTPClient is used as is for voice listing; the websocket dial takes the proxy,
+	// dial and TLS settings of its *http.Transport.
+	HTTPClient *http.Client
+	// NetDialer opens the network connections, including those to a SOCKS5 proxy.
+	NetDialer *net.Dialer
+	// TLSConfig is the TLS configuration of the websocket and of voice listing.
+	TLSConfig *tls.Config
+	// SentenceBoundaryEnabled asks the service to report SentenceBoundary metadata.
+	SentenceBoundaryEnabled bool
+	// MaxRetries is how many times a failed chunk is retried on a new connection.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry; it doubles for every further retry.
+	RetryBackoff time.Duration
+	// Transport replaces the default websocket transport when set.
+	Transport transport.Transport
+	// Endpoint is the websocket synthesis endpoint, without the token query.
+	Endpoint string
+	// TrustedClientToken is sent with every connection and mixed into Sec-MS-GEC.
+	TrustedClientToken string
+	// BrowserVersion is the full Edge version the headers and Sec-MS-GEC-Version claim.
+	BrowserVersion string
+	// Normalizers rewrite the escaped text, in order, before it is split into chunks.
+	// They may insert SSML elements.
+	Normalizers []func(text string) string
+	// MaxChunkBytes caps the size of one text chunk below the message size limit; 0 means no cap.
+	MaxChunkBytes int
+	// ChunkConcurrency is how many chunks are synthesized at once; 0 or 1 is sequential.
+	ChunkConcurrency int
+	// PrefetchWindow is how many chunks may be started ahead of the one being written.
+	PrefetchWindow int
+	// DialTimeout bounds opening a connection; 0 means no limit.
+	DialTimeout time.Duration
+	// FirstByteTimeout bounds the wait for the first audio of a turn; 0 means no limit.
+	FirstByteTimeout time.Duration
+	// IdleTimeout bounds the wait between two messages once audio arrives; 0 means no limit.
+	IdleTimeout time.Duration
 }
 
 func (c *CommunicateOption) CheckAndApplyDefaultOption() {
@@ -45,5 +93,19 @@ func (c *CommunicateOption) CheckAndApplyDefaultOption() {
 	if c.Volume == "" {
 		c.Volume = "+0%"
 	}
-
+	if c.OutputFormat == "" {
+		c.OutputFormat = businessConsts.DefaultOutputFormat
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = businessConsts.EdgeWssEndpoint
+	}
+	if c.TrustedClientToken == "" {
+		c.TrustedClientToken = businessConsts.TrustedClientToken
+	}
+	if c.BrowserVersion == "" {
+		c.BrowserVersion = businessConsts.ChromiumFllVersion
+	}
+	if c.PrefetchWindow <= 0 {
+		c.PrefetchWindow = c.ChunkConcurrency
+	}
 }
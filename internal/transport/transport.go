@@ -0,0 +1,28 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+)
+
+// Message types, matching the websocket opcodes used by the service.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+// Conn is a message-oriented connection to the synthesis service.
+// *websocket.Conn from github.com/gorilla/websocket satisfies it.
+type Conn interface {
+	ReadMessage() (messageType int, data []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	Close() error
+}
+
+// Transport opens connections to the synthesis service.
+type Transport interface {
+	// Dial opens a connection to url sending header with the upgrade request. When the
+	// server rejects the upgrade, the response should be returned along with the error
+	// so that its status code and Date header can be inspected.
+	Dial(ctx context.Context, url string, header http.Header) (Conn, *http.Response, error)
+}
@@ -4,20 +4,31 @@ import (
 	"errors"
 	"regexp"
 
+	"github.com/lib-x/edgetts/internal/businessConsts"
 	"github.com/lib-x/edgetts/internal/communicateOption"
 )
 
 var (
 	validPitchPattern      = regexp.MustCompile(`^[+-]\d+Hz$`)
-	validVoicePattern      = regexp.MustCompile(`^([a-z]{2,})-([A-Z]{2,})-(.+Neural)$`)
+	validVoicePattern      = regexp.MustCompile(`^([a-z]{2,})(-[A-Z][a-z]{3})?-([A-Z]{2,})-(.+Neural)$`)
 	validRateVolumePattern = regexp.MustCompile(`^[+-]\d+%$`)
 )
 
+// validOutputFormats holds the audio formats accepted by the read-aloud endpoint.
+var validOutputFormats = make(map[string]struct{}, len(businessConsts.OutputFormats))
+
+func init() {
+	for _, format := range businessConsts.OutputFormats {
+		validOutputFormats[format] = struct{}{}
+	}
+}
+
 var (
-	InvalidVoiceError  = errors.New("invalid voice")
-	InvalidPitchError  = errors.New("invalid pitch")
-	InvalidRateError   = errors.New("invalid rate")
-	InvalidVolumeError = errors.New("invalid volume")
+	InvalidVoiceError        = errors.New("invalid voice")
+	InvalidPitchError        = errors.New("invalid pitch")
+	InvalidRateError         = errors.New("invalid rate")
+	InvalidVolumeError       = errors.New("invalid volume")
+	InvalidOutputFormatError = errors.New("invalid output format")
 )
 
 // WithCommunicateOption validate With a CommunicateOption
@@ -41,5 +52,10 @@ func WithCommunicateOption(c *communicateOption.CommunicateOption) error {
 		return InvalidVolumeError
 	}
 
+	// WithCommunicateOption output format
+	if _, ok := validOutputFormats[c.OutputFormat]; !ok {
+		return InvalidOutputFormatError
+	}
+
 	return nil
 }
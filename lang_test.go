@@ -0,0 +1,84 @@
+package edgetts_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lib-x/edgetts"
+)
+
+func TestSSMLLanguageFollowsVoice(t *testing.T) {
+	tests := []struct {
+		voice string
+		want  string
+	}{
+		{"", `xml:lang="zh-CN"`},
+		{"en-US-GuyNeural", `xml:lang="en-US"`},
+		// the locale of the voice list, everything before the name
+		{"zh-CN-liaoning-XiaobeiNeural", `xml:lang="zh-CN-liaoning"`},
+		{"sr-Latn-RS-NicholasNeural", `xml:lang="sr-Latn-RS"`},
+	}
+	for _, tt := range tests {
+		var opts []edgetts.Option
+		if tt.voice != "" {
+			opts = append(opts, edgetts.WithVoice(tt.voice))
+		}
+		result, err := edgetts.New(opts...).DryRun(edgetts.Text("hello"))
+		if err != nil {
+			t.Fatalf("DryRun(%q) error = %v", tt.voice, err)
+		}
+		if !strings.Contains(result.SSML[0], tt.want) {
+			t.Errorf("voice %q: SSML = %s, want %s", tt.voice, result.SSML[0], tt.want)
+		}
+	}
+}
+
+func TestSpansWrapOtherLanguages(t *testing.T) {
+	spans := []edgetts.Span{
+		{Text: "请运行 "},
+		{Lang: "en-US", Text: "kubectl apply"},
+		{Text: " 命令"},
+	}
+
+	multilingual := edgetts.New(edgetts.WithVoice("zh-CN-XiaoxiaoMultilingualNeural"))
+	result, err := multilingual.DryRun(edgetts.Spans(spans))
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if want := `请运行 <lang xml:lang="en-US">kubectl apply</lang> 命令`; !strings.Contains(result.SSML[0], want) {
+		t.Fatalf("SSML = %s, want it to contain %s", result.SSML[0], want)
+	}
+
+	monolingual := edgetts.New(edgetts.WithVoice("zh-CN-XiaoxiaoNeural"))
+	result, err = monolingual.DryRun(edgetts.Spans(spans))
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if want := `请运行 kubectl apply 命令`; !strings.Contains(result.SSML[0], want) {
+		t.Fatalf("SSML = %s, want it to contain %s", result.SSML[0], want)
+	}
+}
+
+func TestLongSpanIsSplitAcrossChunks(t *testing.T) {
+	client := edgetts.New(
+		edgetts.WithVoice("en-US-AvaMultilingualNeural"),
+		edgetts.WithMaxChunkBytes(80),
+	)
+	french := strings.Repeat("Bonjour tout le monde. ", 10)
+	result, err := client.DryRun(edgetts.Spans([]edgetts.Span{{Lang: "fr-FR", Text: french}}))
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if len(result.SSML) < 3 {
+		t.Fatalf("turns = %d, want the span split into several chunks", len(result.SSML))
+	}
+	for _, ssml := range result.SSML {
+		if strings.Count(ssml, `<lang xml:lang="fr-FR">`) != strings.Count(ssml, "</lang>") || !strings.Contains(ssml, "</lang>") {
+			t.Fatalf("SSML = %s, want whole lang elements", ssml)
+		}
+	}
+
+	if _, err := client.DryRun(edgetts.Spans([]edgetts.Span{{Lang: "fr-FR", Text: "  "}})); err == nil {
+		t.Fatal("DryRun() of blank spans error = nil, want ErrEmptyInput")
+	}
+}
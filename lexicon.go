@@ -0,0 +1,310 @@
+package edgetts
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrInvalidLexicon = errors.New("invalid lexicon")
+
+// LexiconEntry tells how to pronounce a word or phrase. Set Alias to have the service
+// read other text instead, or Phoneme and Alphabet (e.g. "ipa" or "sapi") to give the
+// exact pronunciation; Alias takes precedence when both are set.
+type LexiconEntry struct {
+	Grapheme string
+	Alias    string
+	Phoneme  string
+	Alphabet string
+}
+
+// LexiconMatch reports an occurrence of a lexicon entry in the text of a request.
+// Text is the occurrence as written, which may differ in case from Entry.Grapheme.
+type LexiconMatch struct {
+	Text  string
+	Entry LexiconEntry
+}
+
+// Lexicon rewrites known words with <sub alias="..."> or <phoneme> elements. A
+// grapheme written in lower case matches in any case, so "nginx" also matches
+// "Nginx" and "NGINX", while a grapheme with upper case letters, such as "SQL", only
+// matches exactly. Graphemes only match whole words, except next to Chinese or
+// Japanese characters, which are not separated by spaces. When graphemes overlap the
+// longest one wins.
+//
+// A Lexicon is also a Normalizer, but it is usually passed to WithLexicon so that it
+// runs after the other normalizers and its matches show up in Client.DryRun.
+type Lexicon struct {
+	entries []lexiconRule
+	// byFirst indexes entries by the folded first rune of their escaped grapheme.
+	byFirst map[rune][]int
+}
+
+type lexiconRule struct {
+	LexiconEntry
+	// escaped is the grapheme as it appears in escaped text.
+	escaped   string
+	foldCase  bool
+	checkLeft bool
+	checkEnd  bool
+}
+
+// NewLexicon returns a lexicon of entries. Entries without a grapheme, or with neither
+// an alias nor a phoneme, are ignored.
+func NewLexicon(entries ...LexiconEntry) *Lexicon {
+	l := &Lexicon{byFirst: make(map[rune][]int)}
+	for _, entry := range entries {
+		entry.Grapheme = strings.TrimSpace(entry.Grapheme)
+		if entry.Grapheme == "" || (entry.Alias == "" && entry.Phoneme == "") {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(entry.Grapheme)
+		last, _ := utf8.DecodeLastRuneInString(entry.Grapheme)
+		l.entries = append(l.entries, lexiconRule{
+			LexiconEntry: entry,
+			escaped:      EscapeXML(entry.Grapheme),
+			foldCase:     entry.Grapheme == strings.ToLower(entry.Grapheme),
+			checkLeft:    needsWordBoundary(first),
+			checkEnd:     needsWordBoundary(last),
+		})
+	}
+	// longest first, so that "Visual Studio Code" wins over "Visual Studio"
+	sort.SliceStable(l.entries, func(i, j int) bool {
+		return len(l.entries[i].escaped) > len(l.entries[j].escaped)
+	})
+	for i, rule := range l.entries {
+		first, _ := utf8.DecodeRuneInString(rule.escaped)
+		key := unicode.ToLower(first)
+		l.byFirst[key] = append(l.byFirst[key], i)
+	}
+	return l
+}
+
+// Entries returns the entries of the lexicon, longest grapheme first.
+func (l *Lexicon) Entries() []LexiconEntry {
+	entries := make([]LexiconEntry, len(l.entries))
+	for i, rule := range l.entries {
+		entries[i] = rule.LexiconEntry
+	}
+	return entries
+}
+
+// LoadLexicon reads a lexicon from a W3C PLS file if path ends in .pls or .xml, and
+// from a CSV file otherwise.
+func LoadLexicon(path string) (*Lexicon, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pls", ".xml":
+		return ParseLexiconPLS(file)
+	default:
+		return ParseLexiconCSV(file)
+	}
+}
+
+// ParseLexiconCSV reads a lexicon with one entry per record. A record of two fields,
+// grapheme and alias, is read as an alias; a record of three fields, grapheme,
+// phoneme and alphabet, as a phoneme. Blank lines and lines starting with # are
+// skipped.
+//
+//	kubectl,cube control
+//	nginx,ˈɛndʒɪn ˈɛks,ipa
+func ParseLexiconCSV(r io.Reader) (*Lexicon, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []LexiconEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLexicon, err)
+		}
+		line, _ := reader.FieldPos(0)
+		switch len(record) {
+		case 2:
+			entries = append(entries, LexiconEntry{Grapheme: record[0], Alias: record[1]})
+		case 3:
+			entries = append(entries, LexiconEntry{Grapheme: record[0], Phoneme: record[1], Alphabet: record[2]})
+		default:
+			return nil, fmt.Errorf("%w: line %d: want 2 or 3 fields, got %d", ErrInvalidLexicon, line, len(record))
+		}
+	}
+	return NewLexicon(entries...), nil
+}
+
+// plsLexicon is the subset of the W3C Pronunciation Lexicon Specification that the
+// service understands.
+type plsLexicon struct {
+	XMLName  xml.Name `xml:"lexicon"`
+	Alphabet string   `xml:"alphabet,attr"`
+	Lexemes  []struct {
+		Graphemes []string `xml:"grapheme"`
+		Aliases   []string `xml:"alias"`
+		Phonemes  []struct {
+			Alphabet string `xml:"alphabet,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"phoneme"`
+	} `xml:"lexeme"`
+}
+
+// ParseLexiconPLS reads a lexicon in the W3C Pronunciation Lexicon Specification
+// format. Every grapheme of a lexeme gets its first alias, or else its first phoneme.
+func ParseLexiconPLS(r io.Reader) (*Lexicon, error) {
+	var pls plsLexicon
+	if err := xml.NewDecoder(r).Decode(&pls); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLexicon, err)
+	}
+
+	var entries []LexiconEntry
+	for i, lexeme := range pls.Lexemes {
+		var entry LexiconEntry
+		switch {
+		case len(lexeme.Aliases) > 0:
+			entry.Alias = strings.TrimSpace(lexeme.Aliases[0])
+		case len(lexeme.Phonemes) > 0:
+			entry.Phoneme = strings.TrimSpace(lexeme.Phonemes[0].Value)
+			entry.Alphabet = lexeme.Phonemes[0].Alphabet
+			if entry.Alphabet == "" {
+				entry.Alphabet = pls.Alphabet
+			}
+		default:
+			return nil, fmt.Errorf("%w: lexeme %d has neither an alias nor a phoneme", ErrInvalidLexicon, i+1)
+		}
+		if len(lexeme.Graphemes) == 0 {
+			return nil, fmt.Errorf("%w: lexeme %d has no grapheme", ErrInvalidLexicon, i+1)
+		}
+		for _, grapheme := range lexeme.Graphemes {
+			entry.Grapheme = grapheme
+			entries = append(entries, entry)
+		}
+	}
+	return NewLexicon(entries...), nil
+}
+
+// WithLexicon rewrites the words of text requests found in lexicons, after any
+// normalizers have run. Earlier lexicons take precedence.
+func WithLexicon(lexicons ...*Lexicon) Option {
+	return func(option *option) {
+		option.Lexicons = append(option.Lexicons, lexicons...)
+	}
+}
+
+func (l *Lexicon) Normalize(text string) string {
+	return l.apply(text, nil)
+}
+
+// apply rewrites the character data of escaped text, reporting every match to record
+// if it is not nil.
+func (l *Lexicon) apply(text string, record func(LexiconMatch)) string {
+	if len(l.entries) == 0 {
+		return text
+	}
+	return TextNormalizer(func(text string) string {
+		var b strings.Builder
+		written := 0
+		for i := 0; i < len(text); {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			rule, ok := l.match(text, i, r)
+			if !ok {
+				// never match inside an entity such as &gt;
+				i += entityLength(text[i:], size)
+				continue
+			}
+			end := i + len(rule.escaped)
+			b.WriteString(text[written:i])
+			b.WriteString(rule.element(text[i:end]))
+			if record != nil {
+				record(LexiconMatch{Text: unescapeXML(text[i:end]), Entry: rule.LexiconEntry})
+			}
+			i, written = end, end
+		}
+		if written == 0 {
+			return text
+		}
+		b.WriteString(text[written:])
+		return b.String()
+	}).Normalize(text)
+}
+
+// entityLength returns the length of the entity text starts with, or else size.
+func entityLength(text string, size int) int {
+	if text[0] != '&' {
+		return size
+	}
+	if end := strings.IndexByte(text, ';'); end > 0 && !strings.ContainsAny(text[1:end], " &<") {
+		return end + 1
+	}
+	return size
+}
+
+// match returns the longest rule matching text at i, whose first rune is r.
+func (l *Lexicon) match(text string, i int, r rune) (lexiconRule, bool) {
+	for _, idx := range l.byFirst[unicode.ToLower(r)] {
+		rule := l.entries[idx]
+		end := i + len(rule.escaped)
+		if end > len(text) {
+			continue
+		}
+		candidate := text[i:end]
+		if candidate != rule.escaped && !(rule.foldCase && strings.EqualFold(candidate, rule.escaped)) {
+			continue
+		}
+		if rule.checkLeft && i > 0 {
+			if before, _ := utf8.DecodeLastRuneInString(text[:i]); isWordRune(before) {
+				continue
+			}
+		}
+		if rule.checkEnd && end < len(text) {
+			if after, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(after) {
+				continue
+			}
+		}
+		return rule, true
+	}
+	return lexiconRule{}, false
+}
+
+// element returns the SSML for an escaped occurrence of the rule's grapheme.
+func (r lexiconRule) element(occurrence string) string {
+	if r.Alias != "" {
+		return `<sub alias="` + EscapeXML(r.Alias) + `">` + occurrence + `</sub>`
+	}
+	alphabet := r.Alphabet
+	if alphabet == "" {
+		alphabet = "ipa"
+	}
+	return `<phoneme alphabet="` + EscapeXML(alphabet) + `" ph="` + EscapeXML(r.Phoneme) + `">` + occurrence + `</phoneme>`
+}
+
+var xmlUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'")
+
+func unescapeXML(s string) string {
+	return xmlUnescaper.Replace(s)
+}
+
+// needsWordBoundary reports whether a grapheme starting or ending with r must be
+// delimited from neighbouring letters. Scripts written without spaces need not be.
+func needsWordBoundary(r rune) bool {
+	return isWordRune(r) && !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,103 @@
+package edgetts_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lib-x/edgetts"
+)
+
+func TestLexiconRewritesWholeWords(t *testing.T) {
+	lexicon := edgetts.NewLexicon(
+		edgetts.LexiconEntry{Grapheme: "Visual Studio", Alias: "V S"},
+		edgetts.LexiconEntry{Grapheme: "Visual Studio Code", Alias: "V S Code"},
+		edgetts.LexiconEntry{Grapheme: "nginx", Phoneme: "ˈɛndʒɪn ˈɛks", Alphabet: "ipa"},
+		edgetts.LexiconEntry{Grapheme: "SQL", Alias: "sequel"},
+		edgetts.LexiconEntry{Grapheme: "AT&T", Alias: "A T and T"},
+		edgetts.LexiconEntry{Grapheme: "微信", Alias: "WeChat"},
+	)
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Visual Studio Code rocks", `<sub alias="V S Code">Visual Studio Code</sub> rocks`},
+		{"NGINX and nginxconf", `<phoneme alphabet="ipa" ph="ˈɛndʒɪn ˈɛks">NGINX</phoneme> and nginxconf`},
+		{"sql or SQL", `sql or <sub alias="sequel">SQL</sub>`},
+		{"call AT&amp;T.", `call <sub alias="A T and T">AT&amp;T</sub>.`},
+		{"用微信支付", `用<sub alias="WeChat">微信</sub>支付`},
+	}
+	for _, tt := range tests {
+		if got := lexicon.Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseLexiconCSV(t *testing.T) {
+	lexicon, err := edgetts.ParseLexiconCSV(strings.NewReader("# product names\nkubectl,cube control\nnginx,ˈɛndʒɪn ˈɛks,ipa\n"))
+	if err != nil {
+		t.Fatalf("ParseLexiconCSV() error = %v", err)
+	}
+	entries := lexicon.Entries()
+	if len(entries) != 2 || entries[0].Alias != "cube control" || entries[1].Alphabet != "ipa" {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	if _, err := edgetts.ParseLexiconCSV(strings.NewReader("a,b,c,d\n")); !errors.Is(err, edgetts.ErrInvalidLexicon) {
+		t.Fatalf("ParseLexiconCSV() error = %v, want ErrInvalidLexicon", err)
+	}
+}
+
+func TestParseLexiconPLS(t *testing.T) {
+	const pls = `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon" alphabet="ipa" xml:lang="en-US">
+  <lexeme><grapheme>tomato</grapheme><phoneme>təˈmɑːtoʊ</phoneme></lexeme>
+  <lexeme><grapheme>W3C</grapheme><grapheme>w3c</grapheme><alias>World Wide Web Consortium</alias></lexeme>
+</lexicon>`
+	lexicon, err := edgetts.ParseLexiconPLS(strings.NewReader(pls))
+	if err != nil {
+		t.Fatalf("ParseLexiconPLS() error = %v", err)
+	}
+	if got, want := lexicon.Normalize("tomato by W3C"), `<phoneme alphabet="ipa" ph="təˈmɑːtoʊ">tomato</phoneme> by <sub alias="World Wide Web Consortium">W3C</sub>`; got != want {
+		t.Fatalf("Normalize() = %q, want %q", got, want)
+	}
+
+	if _, err := edgetts.ParseLexiconPLS(strings.NewReader(`<lexicon><lexeme><grapheme>x</grapheme></lexeme></lexicon>`)); !errors.Is(err, edgetts.ErrInvalidLexicon) {
+		t.Fatalf("ParseLexiconPLS() error = %v, want ErrInvalidLexicon", err)
+	}
+}
+
+func TestDryRunReportsLexiconMatches(t *testing.T) {
+	lexicon := edgetts.NewLexicon(edgetts.LexiconEntry{Grapheme: "kubectl", Alias: "cube control"})
+	client := edgetts.New(edgetts.WithLexicon(lexicon), edgetts.WithMaxChunkBytes(80))
+
+	result, err := client.DryRun(edgetts.Text("Kubectl applies it. Then kubectl waits for the rollout."))
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if len(result.SSML) != 2 || !strings.Contains(result.SSML[1], `<sub alias="cube control">kubectl</sub>`) {
+		t.Fatalf("SSML = %q", result.SSML)
+	}
+	if len(result.LexiconMatches) != 2 || result.LexiconMatches[0].Text != "Kubectl" || result.LexiconMatches[1].Text != "kubectl" {
+		t.Fatalf("LexiconMatches = %+v", result.LexiconMatches)
+	}
+}
+
+func TestLexiconSkipsEntities(t *testing.T) {
+	lexicon := edgetts.NewLexicon(
+		edgetts.LexiconEntry{Grapheme: "gt", Alias: "gran turismo"},
+		edgetts.LexiconEntry{Grapheme: "amp", Alias: "amplifier"},
+	)
+	if got, want := lexicon.Normalize("a &gt; b &amp; the amp"), `a &gt; b &amp; the <sub alias="amplifier">amp</sub>`; got != want {
+		t.Fatalf("Normalize() = %q, want %q", got, want)
+	}
+
+	result, err := edgetts.New(edgetts.WithLexicon(lexicon)).DryRun(edgetts.Text("a > b, my GT"))
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if !strings.Contains(result.SSML[0], `a &gt; b, my <sub alias="gran turismo">GT</sub>`) || len(result.LexiconMatches) != 1 {
+		t.Fatalf("SSML = %q, matches = %+v", result.SSML, result.LexiconMatches)
+	}
+}
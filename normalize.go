@@ -0,0 +1,337 @@
+package edgetts
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalizer rewrites the text of a request before it is split into chunks and
+// synthesized. Text requests are always escaped for XML first, so normalizers receive
+// "&amp;" rather than "&", and they may insert SSML elements such as <say-as> or <sub>.
+// What a normalizer returns is placed in the SSML as is, so any text it adds must be
+// escaped with EscapeXML. Normalizers do not apply to SSML requests.
+type Normalizer interface {
+	Normalize(text string) string
+}
+
+// NormalizerFunc adapts a function to the Normalizer interface.
+type NormalizerFunc func(text string) string
+
+func (f NormalizerFunc) Normalize(text string) string {
+	return f(text)
+}
+
+// WithNormalizers appends normalizers to run, in order, on the text of each request.
+// Register the say-as normalizers from the most to the least specific, e.g. dates and
+// currency before numbers, since text already wrapped in an element is left alone.
+func WithNormalizers(normalizers ...Normalizer) Option {
+	return func(option *option) {
+		option.Normalizers = append(option.Normalizers, normalizers...)
+	}
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+// EscapeXML escapes &, <, >, " and ' so that s can be used as SSML character data or
+// as an attribute value. It is what every text request goes through before normalizers.
+func EscapeXML(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+// TextNormalizer returns a Normalizer that applies f to the character data of the text
+// only. Tags, and the content of elements inserted by earlier normalizers, are passed
+// through untouched, so that for example a number inside a <say-as> date is not
+// wrapped again.
+func TextNormalizer(f func(text string) string) Normalizer {
+	return NormalizerFunc(func(text string) string {
+		if !strings.Contains(text, "<") {
+			return f(text)
+		}
+		var b strings.Builder
+		depth, start := 0, 0
+		for i := 0; i < len(text); i++ {
+			if text[i] != '<' {
+				continue
+			}
+			end := strings.IndexByte(text[i:], '>')
+			if end < 0 {
+				break
+			}
+			if depth == 0 {
+				b.WriteString(f(text[start:i]))
+			} else {
+				b.WriteString(text[start:i])
+			}
+			tag := text[i : i+end+1]
+			switch {
+			case strings.HasPrefix(tag, "</"):
+				depth--
+			case !strings.HasSuffix(tag, "/>"):
+				depth++
+			}
+			b.WriteString(tag)
+			i += end
+			start = i + 1
+		}
+		if depth <= 0 {
+			b.WriteString(f(text[start:]))
+		} else {
+			b.WriteString(text[start:])
+		}
+		return b.String()
+	})
+}
+
+var (
+	blankLinePattern = regexp.MustCompile(`[ \t]*\r?\n[ \t\r]*\n[\s]*`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+)
+
+// CollapseWhitespace returns a Normalizer that replaces runs of whitespace, including
+// single line breaks, with one space. Blank lines are kept as a single paragraph break
+// so that long texts are still chunked at paragraphs. Leading and trailing whitespace
+// is collapsed too rather than removed, so that adjacent spans stay apart.
+func CollapseWhitespace() Normalizer {
+	return NormalizerFunc(func(text string) string {
+		paragraphs := blankLinePattern.Split(text, -1)
+		for i, paragraph := range paragraphs {
+			paragraphs[i] = spacePattern.ReplaceAllString(paragraph, " ")
+		}
+		return strings.Join(paragraphs, "\n\n")
+	})
+}
+
+var (
+	urlPattern   = regexp.MustCompile(`\b(?:https?://|www\.)[^\s<>]+`)
+	emailPattern = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)
+	// urlTrailer matches punctuation that ends the sentence rather than the URL.
+	urlTrailer = regexp.MustCompile(`(?:[.,;:!?)\]]|&apos;|&quot;|&gt;)+$`)
+
+	symbolNames = map[rune]string{
+		'.': "dot",
+		'/': "slash",
+		':': "colon",
+		'-': "dash",
+		'_': "underscore",
+		'@': "at",
+		'?': "question mark",
+		'=': "equals",
+		'&': "and",
+		'#': "hash",
+		'%': "percent",
+		'+': "plus",
+		'~': "tilde",
+	}
+)
+
+// VerbalizeURLs returns a Normalizer that spells out http, https and www URLs in
+// English, e.g. "https://go.dev/doc" becomes "go dot dev slash doc". The scheme and a
+// trailing slash are not read.
+func VerbalizeURLs() Normalizer {
+	return TextNormalizer(func(text string) string {
+		return urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+			trailer := urlTrailer.FindString(match)
+			url := html.UnescapeString(strings.TrimSuffix(match, trailer))
+			url = strings.TrimPrefix(strings.TrimPrefix(url, "http://"), "https://")
+			return verbalize(strings.TrimSuffix(url, "/")) + trailer
+		})
+	})
+}
+
+// VerbalizeEmails returns a Normalizer that spells out email addresses in English, e.g.
+// "jane.doe@example.com" becomes "jane dot doe at example dot com".
+func VerbalizeEmails() Normalizer {
+	return TextNormalizer(func(text string) string {
+		return emailPattern.ReplaceAllStringFunc(text, verbalize)
+	})
+}
+
+// verbalize replaces the symbols of an unescaped address with their names.
+func verbalize(address string) string {
+	words := make([]string, 0, 8)
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, EscapeXML(word.String()))
+			word.Reset()
+		}
+	}
+	for _, r := range address {
+		if name, ok := symbolNames[r]; ok {
+			flush()
+			words = append(words, name)
+			continue
+		}
+		word.WriteRune(r)
+	}
+	flush()
+	return strings.Join(words, " ")
+}
+
+// DefaultEmojiNames names a few common emoji in English for NameEmoji.
+var DefaultEmojiNames = map[string]string{
+	"😀": "grinning face",
+	"😂": "face with tears of joy",
+	"😊": "smiling face",
+	"🙂": "slightly smiling face",
+	"😉": "winking face",
+	"😍": "heart eyes",
+	"😢": "crying face",
+	"😡": "angry face",
+	"👍": "thumbs up",
+	"👎": "thumbs down",
+	"👏": "clapping hands",
+	"🙏": "folded hands",
+	"❤": "red heart",
+	"🔥": "fire",
+	"🎉": "party popper",
+	"✅": "check mark",
+	"❌": "cross mark",
+	"⭐": "star",
+	"🚀": "rocket",
+	"💡": "light bulb",
+}
+
+// StripEmoji returns a Normalizer that removes emoji, which the service otherwise
+// either skips or reads out by their Unicode names depending on the voice.
+func StripEmoji() Normalizer {
+	return replaceEmoji(func(string) string { return "" })
+}
+
+// NameEmoji returns a Normalizer that replaces emoji with their names from names, or
+// from DefaultEmojiNames when names is nil. Emoji without a name are removed. Keys are
+// looked up without variation selectors, so "❤" also matches "❤️".
+func NameEmoji(names map[string]string) Normalizer {
+	if names == nil {
+		names = DefaultEmojiNames
+	}
+	return replaceEmoji(func(emoji string) string {
+		if name, ok := names[strings.ReplaceAll(emoji, "\uFE0F", "")]; ok {
+			return EscapeXML(name)
+		}
+		// fall back to the first code point of a sequence, e.g. a thumbs up with a skin tone
+		r, _ := utf8.DecodeRuneInString(emoji)
+		return EscapeXML(names[string(r)])
+	})
+}
+
+// replaceEmoji replaces every emoji sequence, including its modifiers and zero width
+// joiners, with the result of name. Non-empty names are separated from adjacent words
+// by a space.
+func replaceEmoji(name func(emoji string) string) Normalizer {
+	return NormalizerFunc(func(text string) string {
+		var b strings.Builder
+		for i := 0; i < len(text); {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !isEmoji(r) {
+				b.WriteString(text[i : i+size])
+				i += size
+				continue
+			}
+			end := i + size
+			for end < len(text) {
+				next, nextSize := utf8.DecodeRuneInString(text[end:])
+				// modifiers and emoji joined by a zero width joiner continue the sequence
+				if !isEmojiModifier(next) && !(isEmoji(next) && strings.HasSuffix(text[:end], "\u200D")) {
+					break
+				}
+				end += nextSize
+			}
+			replacement := name(text[i:end])
+			if replacement != "" {
+				if b.Len() > 0 && !isSpaceAt(b.String(), b.Len()-1) {
+					b.WriteByte(' ')
+				}
+				b.WriteString(replacement)
+				if end < len(text) && !isSpaceAt(text, end) {
+					b.WriteByte(' ')
+				}
+			}
+			i = end
+		}
+		return b.String()
+	})
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF,
+		r >= 0x2600 && r <= 0x27BF,
+		r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	default:
+		return false
+	}
+}
+
+// isEmojiModifier reports whether r only modifies the emoji before it: variation
+// selectors, zero width joiners, skin tones, keycaps and tag characters.
+func isEmojiModifier(r rune) bool {
+	switch {
+	case r == 0xFE0E, r == 0xFE0F, r == 0x200D, r == 0x20E3,
+		r >= 0x1F3FB && r <= 0x1F3FF,
+		r >= 0xE0020 && r <= 0xE007F:
+		return true
+	default:
+		return false
+	}
+}
+
+func isSpaceAt(s string, i int) bool {
+	return unicode.IsSpace(rune(s[i]))
+}
+
+var (
+	numberPattern    = regexp.MustCompile(`\b\d+(?:,\d{3})*(?:\.\d+)?\b`)
+	ordinalPattern   = regexp.MustCompile(`\b\d+(?:st|nd|rd|th)\b`)
+	isoDatePattern   = regexp.MustCompile(`\b\d{4}-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])\b`)
+	slashDatePattern = regexp.MustCompile(`\b\d{1,2}/\d{1,2}/\d{4}\b`)
+	currencyPattern  = regexp.MustCompile(`(?:[$€£¥] ?|\b(?:USD|EUR|GBP|JPY|CNY|CAD|AUD) ?)\d+(?:,\d{3})*(?:\.\d+)?\b`)
+)
+
+// SayAsNumbers returns a Normalizer that wraps numbers such as 42, 3.14 and 1,000,000
+// in <say-as interpret-as="cardinal"> and ordinals such as 21st in
+// <say-as interpret-as="ordinal">. Digits that are part of a word, as in mp3, are left
+// alone.
+func SayAsNumbers() Normalizer {
+	return TextNormalizer(func(text string) string {
+		text = ordinalPattern.ReplaceAllString(text, sayAs("ordinal", "", "$0"))
+		return TextNormalizer(func(text string) string {
+			return numberPattern.ReplaceAllString(text, sayAs("cardinal", "", "$0"))
+		}).Normalize(text)
+	})
+}
+
+// SayAsDates returns a Normalizer that wraps ISO 8601 dates such as 2024-05-01 in
+// <say-as interpret-as="date" format="ymd">. Dates written with slashes, such as
+// 05/01/2024, are ambiguous and only wrapped when slashFormat is "mdy" or "dmy".
+func SayAsDates(slashFormat string) Normalizer {
+	return TextNormalizer(func(text string) string {
+		text = isoDatePattern.ReplaceAllString(text, sayAs("date", "ymd", "$0"))
+		if slashFormat != "mdy" && slashFormat != "dmy" {
+			return text
+		}
+		return TextNormalizer(func(text string) string {
+			return slashDatePattern.ReplaceAllString(text, sayAs("date", slashFormat, "$0"))
+		}).Normalize(text)
+	})
+}
+
+// SayAsCurrency returns a Normalizer that wraps amounts such as $12.50, € 3 or
+// USD 1,200 in <say-as interpret-as="currency">.
+func SayAsCurrency() Normalizer {
+	return TextNormalizer(func(text string) string {
+		return currencyPattern.ReplaceAllString(text, sayAs("currency", "", "$0"))
+	})
+}
+
+// sayAs returns a say-as element around content, which may be a regexp template.
+func sayAs(interpretAs, format, content string) string {
+	if format != "" {
+		return `<say-as interpret-as="` + interpretAs + `" format="` + format + `">` + content + `</say-as>`
+	}
+	return `<say-as interpret-as="` + interpretAs + `">` + content + `</say-as>`
+}
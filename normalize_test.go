@@ -0,0 +1,109 @@
+package edgetts_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lib-x/edgetts"
+	"github.com/lib-x/edgetts/edgettstest"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer edgetts.Normalizer
+		in         string
+		want       string
+	}{
+		{
+			name:       "collapse whitespace",
+			normalizer: edgetts.CollapseWhitespace(),
+			in:         "  one\t two\nthree \n\n\n four  ",
+			want:       " one two three\n\nfour ",
+		},
+		{
+			name:       "url",
+			normalizer: edgetts.VerbalizeURLs(),
+			in:         "See https://go.dev/doc/.",
+			want:       "See go dot dev slash doc.",
+		},
+		{
+			name:       "url with escaped query",
+			normalizer: edgetts.VerbalizeURLs(),
+			in:         "Open www.example.com/?a=1&amp;b=2",
+			want:       "Open www dot example dot com slash question mark a equals 1 and b equals 2",
+		},
+		{
+			name:       "email",
+			normalizer: edgetts.VerbalizeEmails(),
+			in:         "Mail jane.doe@example.com today",
+			want:       "Mail jane dot doe at example dot com today",
+		},
+		{
+			name:       "strip emoji",
+			normalizer: edgetts.StripEmoji(),
+			in:         "Ship it 🚀👍🏽!",
+			want:       "Ship it !",
+		},
+		{
+			name:       "name emoji",
+			normalizer: edgetts.NameEmoji(nil),
+			in:         "I ❤️Go👍🏽",
+			want:       "I red heart Go thumbs up",
+		},
+		{
+			name:       "numbers",
+			normalizer: edgetts.SayAsNumbers(),
+			in:         "The 21st of 1,000 mp3 files",
+			want:       `The <say-as interpret-as="ordinal">21st</say-as> of <say-as interpret-as="cardinal">1,000</say-as> mp3 files`,
+		},
+		{
+			name:       "dates",
+			normalizer: edgetts.SayAsDates("dmy"),
+			in:         "2024-05-01 or 01/05/2024",
+			want:       `<say-as interpret-as="date" format="ymd">2024-05-01</say-as> or <say-as interpret-as="date" format="dmy">01/05/2024</say-as>`,
+		},
+		{
+			name:       "currency",
+			normalizer: edgetts.SayAsCurrency(),
+			in:         "Pay $12.50 or USD 1,200",
+			want:       `Pay <say-as interpret-as="currency">$12.50</say-as> or <say-as interpret-as="currency">USD 1,200</say-as>`,
+		},
+		{
+			name:       "wrapped text is left alone",
+			normalizer: edgetts.SayAsNumbers(),
+			in:         `<say-as interpret-as="date" format="ymd">2024-05-01</say-as> and 3`,
+			want:       `<say-as interpret-as="date" format="ymd">2024-05-01</say-as> and <say-as interpret-as="cardinal">3</say-as>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer.Normalize(tt.in); got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithNormalizersRewritesSSML(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	nickname := edgetts.TextNormalizer(strings.NewReplacer("Jerry", "Jerry the mouse").Replace)
+	client := edgetts.New(server.Option(), edgetts.WithNormalizers(edgetts.SayAsDates(""), nickname))
+	if _, err := client.Bytes(context.Background(), `Tom & "Jerry" <3 on 2024-05-01`); err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+
+	turns := server.Turns()
+	if len(turns) != 1 {
+		t.Fatalf("turns = %d, want 1", len(turns))
+	}
+	if want := `Tom &amp; &quot;Jerry the mouse&quot; &lt;3 on <say-as interpret-as="date" format="ymd">2024-05-01</say-as>`; !strings.Contains(turns[0].SSML, want) {
+		t.Fatalf("SSML = %s, want it to contain %s", turns[0].SSML, want)
+	}
+	if want := `Tom & "Jerry the mouse" <3 on 2024-05-01`; turns[0].Text != want {
+		t.Fatalf("Text = %q, want %q", turns[0].Text, want)
+	}
+}
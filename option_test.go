@@ -22,3 +22,11 @@ func TestOptionAliases(t *testing.T) {
 		t.Fatalf("unexpected option state: %+v", opt)
 	}
 }
+
+func TestWithSentenceBoundaries(t *testing.T) {
+	opt := &option{}
+	WithSentenceBoundaries()(opt)
+	if !opt.toInternalOption().SentenceBoundaryEnabled {
+		t.Fatal("expected sentence boundaries to be enabled")
+	}
+}
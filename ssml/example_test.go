@@ -0,0 +1,20 @@
+package ssml_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib-x/edgetts/ssml"
+)
+
+func Example() {
+	doc := ssml.NewSpeak("en-US",
+		ssml.NewVoice("en-US-AriaNeural",
+			ssml.NewProsody(ssml.Text("Hello & welcome")).WithRate("+10%"),
+			ssml.NewBreak(300*time.Millisecond),
+		),
+	)
+	fmt.Println(doc)
+	// Output:
+	// <speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xmlns:mstts="https://www.w3.org/2001/mstts" xml:lang="en-US"><voice name="en-US-AriaNeural"><prosody rate="+10%">Hello &amp; welcome</prosody><break time="300ms"/></voice></speak>
+}
@@ -0,0 +1,269 @@
+package ssml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse reads an SSML document into its tree. Elements this package has no type for
+// become an Element, and text, including whitespace between elements, and comments are
+// kept. Attributes the typed fields do not cover, namespace declarations and prefixes
+// are kept too, so that rendering the result reproduces the document up to attribute
+// order and escaping. Processing instructions are dropped.
+func Parse(r io.Reader) (*Speak, error) {
+	p := &parser{dec: xml.NewDecoder(r), prefixes: make(map[string][]string)}
+	for {
+		token, err := p.dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("ssml: no speak element")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ssml: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "speak" {
+			return nil, fmt.Errorf("ssml: root element is %s, want speak", start.Name.Local)
+		}
+		if start.Name.Space != Namespace && start.Name.Space != "" {
+			return nil, fmt.Errorf("ssml: root element is in namespace %s, want %s", start.Name.Space, Namespace)
+		}
+		node, err := p.parseElement(start)
+		if err != nil {
+			return nil, err
+		}
+		speak, ok := node.(*Speak)
+		if !ok {
+			return nil, errors.New("ssml: root element is not speak")
+		}
+		return speak, nil
+	}
+}
+
+// ParseString reads an SSML document from s.
+func ParseString(s string) (*Speak, error) {
+	return Parse(strings.NewReader(s))
+}
+
+type parser struct {
+	dec *xml.Decoder
+	// prefixes maps a namespace to the prefixes declared for it by the elements being
+	// read, innermost last. The decoder resolves prefixes, and they are restored from it.
+	prefixes map[string][]string
+}
+
+// parseElement reads the content of start up to its end tag.
+func (p *parser) parseElement(start xml.StartElement) (Node, error) {
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" {
+			p.prefixes[a.Value] = append(p.prefixes[a.Value], a.Name.Local)
+			defer func() { p.prefixes[a.Value] = p.prefixes[a.Value][:len(p.prefixes[a.Value])-1] }()
+		}
+	}
+	children, text, err := p.parseContent()
+	if err != nil {
+		return nil, err
+	}
+	attrs := &attributes{parser: p, attrs: start.Attr, taken: make([]bool, len(start.Attr))}
+	attr := attrs.take
+
+	if start.Name.Space == MSTTSNamespace {
+		if start.Name.Local == "express-as" {
+			e := &ExpressAs{Style: attr("style"), Role: attr("role"), Children: children}
+			attrs.takeIf("styledegree", func(value string) bool {
+				degree, err := strconv.ParseFloat(value, 64)
+				e.StyleDegree = degree
+				return err == nil && degree != 0
+			})
+			e.Attrs = attrs.rest()
+			return e, nil
+		}
+		return p.newElement(start, children), nil
+	}
+	if start.Name.Space != Namespace && start.Name.Space != "" {
+		return p.newElement(start, children), nil
+	}
+	switch start.Name.Local {
+	case "speak":
+		s := &Speak{Lang: attr("xml:lang"), Children: children}
+		// the attributes Render writes itself
+		attrs.takeIf("version", func(value string) bool { return value == "1.0" })
+		attrs.takeIf("xmlns", func(value string) bool { return value == Namespace })
+		attrs.takeIf("xmlns:mstts", func(value string) bool { return value == MSTTSNamespace })
+		s.Attrs = attrs.rest()
+		return s, nil
+	case "voice":
+		return &Voice{Name: attr("name"), Attrs: attrs.rest(), Children: children}, nil
+	case "lang":
+		return &Lang{Lang: attr("xml:lang"), Attrs: attrs.rest(), Children: children}, nil
+	case "prosody":
+		contour, err := parseContour(attr("contour"))
+		if err != nil {
+			return nil, err
+		}
+		return &Prosody{
+			Pitch:    attr("pitch"),
+			Contour:  contour,
+			Range:    attr("range"),
+			Rate:     attr("rate"),
+			Volume:   attr("volume"),
+			Attrs:    attrs.rest(),
+			Children: children,
+		}, nil
+	case "break":
+		b := &Break{Strength: attr("strength")}
+		if value := attr("time"); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("ssml: break time %q: %w", value, err)
+			}
+			b.Time = d
+		}
+		b.Attrs = attrs.rest()
+		return b, nil
+	case "emphasis":
+		return &Emphasis{Level: attr("level"), Attrs: attrs.rest(), Children: children}, nil
+	case "say-as":
+		return &SayAs{InterpretAs: attr("interpret-as"), Format: attr("format"), Detail: attr("detail"), Text: text, Attrs: attrs.rest()}, nil
+	case "sub":
+		return &Sub{Alias: attr("alias"), Text: text, Attrs: attrs.rest()}, nil
+	case "phoneme":
+		return &Phoneme{Alphabet: attr("alphabet"), Ph: attr("ph"), Text: text, Attrs: attrs.rest()}, nil
+	case "p":
+		return &Paragraph{Attrs: attrs.rest(), Children: children}, nil
+	case "s":
+		return &Sentence{Attrs: attrs.rest(), Children: children}, nil
+	default:
+		return p.newElement(start, children), nil
+	}
+}
+
+// parseContent reads nodes up to the end tag of the current element. text is the
+// character data of the whole content.
+func (p *parser) parseContent() (children []Node, text string, err error) {
+	var b strings.Builder
+	for {
+		token, err := p.dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, "", fmt.Errorf("ssml: %w", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := p.parseElement(token)
+			if err != nil {
+				return nil, "", err
+			}
+			children = append(children, child)
+		case xml.CharData:
+			children = append(children, Text(token))
+			b.Write(token)
+		case xml.Comment:
+			children = append(children, Comment(token))
+		case xml.EndElement:
+			return children, b.String(), nil
+		}
+	}
+}
+
+// newElement returns an Element for start with all of its attributes.
+func (p *parser) newElement(start xml.StartElement, children []Node) *Element {
+	attrs := &attributes{parser: p, attrs: start.Attr, taken: make([]bool, len(start.Attr))}
+	return &Element{Name: p.qualifiedName(start.Name), Attrs: attrs.rest(), Children: children}
+}
+
+// qualifiedName restores the prefix of a name resolved by the decoder. Names in the
+// default namespace, and the mstts and xml prefixes, need no declaration in scope.
+func (p *parser) qualifiedName(name xml.Name) string {
+	switch name.Space {
+	case "":
+		return name.Local
+	case "xmlns", xmlNamespace:
+		// xmlns declarations are not resolved, and xml is predeclared
+		prefix := name.Space
+		if prefix == xmlNamespace {
+			prefix = "xml"
+		}
+		return prefix + ":" + name.Local
+	}
+	if prefixes := p.prefixes[name.Space]; len(prefixes) > 0 {
+		return prefixes[len(prefixes)-1] + ":" + name.Local
+	}
+	if name.Space == MSTTSNamespace {
+		return "mstts:" + name.Local
+	}
+	return name.Local
+}
+
+// attributes hands the attributes of an element to the typed fields, leaving the
+// others for Attrs.
+type attributes struct {
+	parser *parser
+	attrs  []xml.Attr
+	taken  []bool
+}
+
+// take returns the value of the attribute with the qualified name, unless it is empty:
+// an empty value is left for Attrs.
+func (a *attributes) take(name string) string {
+	var value string
+	a.takeIf(name, func(v string) bool {
+		value = v
+		return v != ""
+	})
+	return value
+}
+
+// takeIf takes the attribute with the qualified name if keep accepts its value.
+func (a *attributes) takeIf(name string, keep func(value string) bool) {
+	for i, attr := range a.attrs {
+		if a.taken[i] || a.parser.qualifiedName(attr.Name) != name {
+			continue
+		}
+		a.taken[i] = keep(attr.Value)
+		return
+	}
+}
+
+// rest returns the attributes not taken.
+func (a *attributes) rest() []Attr {
+	var attrs []Attr
+	for i, attr := range a.attrs {
+		if !a.taken[i] {
+			attrs = append(attrs, Attr{Name: a.parser.qualifiedName(attr.Name), Value: attr.Value})
+		}
+	}
+	return attrs
+}
+
+var contourPointPattern = regexp.MustCompile(`\(\s*([0-9.]+)%\s*,\s*([^)]*?)\s*\)`)
+
+// parseContour parses a contour attribute such as "(0%,+20Hz) (60%,-10%)".
+func parseContour(value string) ([]ContourPoint, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	matches := contourPointPattern.FindAllStringSubmatch(value, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("ssml: invalid contour %q", value)
+	}
+	points := make([]ContourPoint, len(matches))
+	for i, match := range matches {
+		position, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("ssml: invalid contour %q: %w", value, err)
+		}
+		points[i] = ContourPoint{Position: position, Pitch: match[2]}
+	}
+	return points, nil
+}
@@ -0,0 +1,174 @@
+package ssml
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+)
+
+// Render renders nodes as XML. Use it for fragments; Speak.String renders a document.
+func Render(nodes ...Node) string {
+	w := &writer{}
+	for _, node := range nodes {
+		node.render(w)
+	}
+	return w.String()
+}
+
+type writer struct {
+	strings.Builder
+}
+
+// open writes a start tag with the attributes whose value is not empty, followed by
+// extra. attrs holds name and value pairs; those named in extra are left out.
+func (w *writer) open(name string, extra []Attr, attrs ...string) {
+	w.startTag(name, extra, attrs)
+	w.WriteByte('>')
+}
+
+// empty writes an element without content as a self-closing tag.
+func (w *writer) empty(name string, extra []Attr, attrs ...string) {
+	w.startTag(name, extra, attrs)
+	w.WriteString("/>")
+}
+
+func (w *writer) startTag(name string, extra []Attr, attrs []string) {
+	w.WriteByte('<')
+	w.WriteString(name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] == "" || slices.ContainsFunc(extra, func(a Attr) bool { return a.Name == attrs[i] }) {
+			continue
+		}
+		w.attr(attrs[i], attrs[i+1])
+	}
+	for _, a := range extra {
+		w.attr(a.Name, a.Value)
+	}
+}
+
+func (w *writer) attr(name, value string) {
+	w.WriteByte(' ')
+	w.WriteString(name)
+	w.WriteString(`="`)
+	attrEscaper.WriteString(w, value)
+	w.WriteByte('"')
+}
+
+func (w *writer) close(name string) {
+	w.WriteString("</")
+	w.WriteString(name)
+	w.WriteByte('>')
+}
+
+func (w *writer) element(name string, extra []Attr, children []Node, attrs ...string) {
+	w.open(name, extra, attrs...)
+	for _, child := range children {
+		child.render(w)
+	}
+	w.close(name)
+}
+
+func (w *writer) textElement(name string, extra []Attr, text string, attrs ...string) {
+	w.open(name, extra, attrs...)
+	textEscaper.WriteString(w, text)
+	w.close(name)
+}
+
+func (t Text) render(w *writer) {
+	textEscaper.WriteString(w, string(t))
+}
+
+func (c Comment) render(w *writer) {
+	w.WriteString("<!--")
+	w.WriteString(string(c))
+	w.WriteString("-->")
+}
+
+func (s *Speak) render(w *writer) {
+	w.element("speak", s.Attrs, s.Children,
+		"version", "1.0",
+		"xmlns", Namespace,
+		"xmlns:mstts", MSTTSNamespace,
+		"xml:lang", s.Lang,
+	)
+}
+
+func (v *Voice) render(w *writer) {
+	w.element("voice", v.Attrs, v.Children, "name", v.Name)
+}
+
+func (l *Lang) render(w *writer) {
+	w.element("lang", l.Attrs, l.Children, "xml:lang", l.Lang)
+}
+
+func (p *Prosody) render(w *writer) {
+	w.element("prosody", p.Attrs, p.Children,
+		"pitch", p.Pitch,
+		"contour", formatContour(p.Contour),
+		"range", p.Range,
+		"rate", p.Rate,
+		"volume", p.Volume,
+	)
+}
+
+// formatContour formats points as the service expects, e.g. "(0%,+20Hz) (60%,-10%)".
+func formatContour(points []ContourPoint) string {
+	parts := make([]string, len(points))
+	for i, point := range points {
+		parts[i] = "(" + strconv.FormatFloat(point.Position, 'f', -1, 64) + "%," + point.Pitch + ")"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (b *Break) render(w *writer) {
+	attrs := []string{"strength", b.Strength}
+	if b.Time > 0 {
+		attrs = []string{"time", strconv.FormatInt(b.Time.Milliseconds(), 10) + "ms"}
+	}
+	w.empty("break", b.Attrs, attrs...)
+}
+
+func (e *Emphasis) render(w *writer) {
+	w.element("emphasis", e.Attrs, e.Children, "level", e.Level)
+}
+
+func (s *SayAs) render(w *writer) {
+	w.textElement("say-as", s.Attrs, s.Text, "interpret-as", s.InterpretAs, "format", s.Format, "detail", s.Detail)
+}
+
+func (s *Sub) render(w *writer) {
+	w.textElement("sub", s.Attrs, s.Text, "alias", s.Alias)
+}
+
+func (p *Phoneme) render(w *writer) {
+	w.textElement("phoneme", p.Attrs, p.Text, "alphabet", p.Alphabet, "ph", p.Ph)
+}
+
+func (p *Paragraph) render(w *writer) {
+	w.element("p", p.Attrs, p.Children)
+}
+
+func (s *Sentence) render(w *writer) {
+	w.element("s", s.Attrs, s.Children)
+}
+
+func (e *ExpressAs) render(w *writer) {
+	var degree string
+	if e.StyleDegree != 0 {
+		degree = strconv.FormatFloat(e.StyleDegree, 'f', -1, 64)
+	}
+	w.element("mstts:express-as", e.Attrs, e.Children, "style", e.Style, "styledegree", degree, "role", e.Role)
+}
+
+func (e *Element) render(w *writer) {
+	if len(e.Children) == 0 {
+		w.empty(e.Name, e.Attrs)
+		return
+	}
+	w.element(e.Name, e.Attrs, e.Children)
+}
@@ -0,0 +1,310 @@
+// Package ssml builds and parses Speech Synthesis Markup Language documents, including
+// the mstts extensions understood by the Edge read aloud service.
+//
+// Documents are trees of typed nodes. Build them with the New functions and the fluent
+// With and Add methods, render them with String, and pass the result to edgetts.SSML:
+//
+//	doc := ssml.NewSpeak("en-US",
+//		ssml.NewVoice("en-US-AriaNeural",
+//			ssml.NewProsody(ssml.Text("Hello & welcome")).WithRate("+10%"),
+//			ssml.NewBreak(300*time.Millisecond),
+//		),
+//	)
+//	audio, err := client.BytesSSML(ctx, doc.String())
+//
+// Text is escaped when rendered, so it never needs escaping by hand.
+package ssml
+
+import "time"
+
+const (
+	// Namespace is the namespace of SSML elements.
+	Namespace = "http://www.w3.org/2001/10/synthesis"
+	// MSTTSNamespace is the namespace of the Microsoft extensions, bound to the mstts prefix.
+	MSTTSNamespace = "https://www.w3.org/2001/mstts"
+
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// Node is an element or a run of text in an SSML document.
+type Node interface {
+	render(w *writer)
+}
+
+// Text is character data. It is escaped when rendered.
+type Text string
+
+// Comment is an XML comment. It must not contain "--".
+type Comment string
+
+// Speak is the root element of a document.
+type Speak struct {
+	// Lang is the xml:lang of the document, e.g. en-US.
+	Lang     string
+	Attrs    []Attr
+	Children []Node
+}
+
+// NewSpeak returns a document in lang.
+func NewSpeak(lang string, children ...Node) *Speak {
+	return &Speak{Lang: lang, Children: children}
+}
+
+// Add appends children to the document.
+func (s *Speak) Add(children ...Node) *Speak {
+	s.Children = append(s.Children, children...)
+	return s
+}
+
+// String renders the document as XML.
+func (s *Speak) String() string {
+	return Render(s)
+}
+
+// Voice selects the voice that speaks its content, e.g. en-US-AriaNeural.
+type Voice struct {
+	Name     string
+	Attrs    []Attr
+	Children []Node
+}
+
+func NewVoice(name string, children ...Node) *Voice {
+	return &Voice{Name: name, Children: children}
+}
+
+func (v *Voice) Add(children ...Node) *Voice {
+	v.Children = append(v.Children, children...)
+	return v
+}
+
+// Lang switches the language of its content for multilingual voices.
+type Lang struct {
+	Lang     string
+	Attrs    []Attr
+	Children []Node
+}
+
+func NewLang(lang string, children ...Node) *Lang {
+	return &Lang{Lang: lang, Children: children}
+}
+
+func (l *Lang) Add(children ...Node) *Lang {
+	l.Children = append(l.Children, children...)
+	return l
+}
+
+// Prosody changes the pitch, rate and volume of its content. Values use the service
+// syntax, e.g. "+10%", "-2st", "+50Hz" or "x-loud". Contour overrides Pitch with pitch
+// targets at positions within the content.
+type Prosody struct {
+	Pitch    string
+	Contour  []ContourPoint
+	Range    string
+	Rate     string
+	Volume   string
+	Attrs    []Attr
+	Children []Node
+}
+
+// ContourPoint is a pitch target at Position percent of the duration of the content.
+type ContourPoint struct {
+	Position float64
+	Pitch    string
+}
+
+func NewProsody(children ...Node) *Prosody {
+	return &Prosody{Children: children}
+}
+
+func (p *Prosody) WithPitch(pitch string) *Prosody {
+	p.Pitch = pitch
+	return p
+}
+
+func (p *Prosody) WithContour(points ...ContourPoint) *Prosody {
+	p.Contour = points
+	return p
+}
+
+func (p *Prosody) WithRange(pitchRange string) *Prosody {
+	p.Range = pitchRange
+	return p
+}
+
+func (p *Prosody) WithRate(rate string) *Prosody {
+	p.Rate = rate
+	return p
+}
+
+func (p *Prosody) WithVolume(volume string) *Prosody {
+	p.Volume = volume
+	return p
+}
+
+func (p *Prosody) Add(children ...Node) *Prosody {
+	p.Children = append(p.Children, children...)
+	return p
+}
+
+// Break inserts a pause of Time, or of Strength: none, x-weak, weak, medium, strong
+// or x-strong. Time takes precedence when both are set.
+type Break struct {
+	Strength string
+	Time     time.Duration
+	Attrs    []Attr
+}
+
+// NewBreak returns a pause of d; pass 0 and use WithStrength for a relative pause.
+func NewBreak(d time.Duration) *Break {
+	return &Break{Time: d}
+}
+
+func (b *Break) WithStrength(strength string) *Break {
+	b.Strength = strength
+	return b
+}
+
+// Emphasis stresses its content at Level: reduced, none, moderate or strong.
+type Emphasis struct {
+	Level    string
+	Attrs    []Attr
+	Children []Node
+}
+
+func NewEmphasis(level string, children ...Node) *Emphasis {
+	return &Emphasis{Level: level, Children: children}
+}
+
+func (e *Emphasis) Add(children ...Node) *Emphasis {
+	e.Children = append(e.Children, children...)
+	return e
+}
+
+// SayAs tells how to read Text, e.g. InterpretAs "date" with Format "ymd", or
+// "cardinal", "ordinal", "characters", "telephone" and "time".
+type SayAs struct {
+	InterpretAs string
+	Format      string
+	Detail      string
+	Text        string
+	Attrs       []Attr
+}
+
+func NewSayAs(interpretAs, text string) *SayAs {
+	return &SayAs{InterpretAs: interpretAs, Text: text}
+}
+
+func (s *SayAs) WithFormat(format string) *SayAs {
+	s.Format = format
+	return s
+}
+
+func (s *SayAs) WithDetail(detail string) *SayAs {
+	s.Detail = detail
+	return s
+}
+
+// Sub reads Alias in place of Text.
+type Sub struct {
+	Alias string
+	Text  string
+	Attrs []Attr
+}
+
+func NewSub(alias, text string) *Sub {
+	return &Sub{Alias: alias, Text: text}
+}
+
+// Phoneme pronounces Text as Ph, written in Alphabet: ipa, sapi or ups.
+type Phoneme struct {
+	Alphabet string
+	Ph       string
+	Text     string
+	Attrs    []Attr
+}
+
+func NewPhoneme(alphabet, ph, text string) *Phoneme {
+	return &Phoneme{Alphabet: alphabet, Ph: ph, Text: text}
+}
+
+// Paragraph marks its content as a paragraph (p).
+type Paragraph struct {
+	Attrs    []Attr
+	Children []Node
+}
+
+func NewParagraph(children ...Node) *Paragraph {
+	return &Paragraph{Children: children}
+}
+
+func (p *Paragraph) Add(children ...Node) *Paragraph {
+	p.Children = append(p.Children, children...)
+	return p
+}
+
+// Sentence marks its content as a sentence (s).
+type Sentence struct {
+	Attrs    []Attr
+	Children []Node
+}
+
+func NewSentence(children ...Node) *Sentence {
+	return &Sentence{Children: children}
+}
+
+func (s *Sentence) Add(children ...Node) *Sentence {
+	s.Children = append(s.Children, children...)
+	return s
+}
+
+// ExpressAs speaks its content in a speaking style such as cheerful or sad
+// (mstts:express-as). StyleDegree scales the style from 0.01 to 2, and Role makes the
+// voice imitate another age or gender; both are left out when zero.
+type ExpressAs struct {
+	Style       string
+	StyleDegree float64
+	Role        string
+	Attrs       []Attr
+	Children    []Node
+}
+
+func NewExpressAs(style string, children ...Node) *ExpressAs {
+	return &ExpressAs{Style: style, Children: children}
+}
+
+func (e *ExpressAs) WithStyleDegree(degree float64) *ExpressAs {
+	e.StyleDegree = degree
+	return e
+}
+
+func (e *ExpressAs) WithRole(role string) *ExpressAs {
+	e.Role = role
+	return e
+}
+
+func (e *ExpressAs) Add(children ...Node) *ExpressAs {
+	e.Children = append(e.Children, children...)
+	return e
+}
+
+// Element is any other element, such as mstts:silence or bookmark. Name carries the
+// prefix, if any, and Parse produces an Element for every element it does not know.
+type Element struct {
+	Name     string
+	Attrs    []Attr
+	Children []Node
+}
+
+// Attr is an attribute. Every element keeps the attributes its other fields do not
+// cover, including namespace declarations, in Attrs, which Parse fills so that a parsed
+// document renders with all of its attributes. They are rendered after the others, even
+// when empty, and replace a built-in attribute of the same name, such as the version of
+// speak.
+type Attr struct {
+	Name  string
+	Value string
+}
+
+func NewElement(name string, attrs []Attr, children ...Node) *Element {
+	return &Element{Name: name, Attrs: attrs, Children: children}
+}
@@ -0,0 +1,117 @@
+package ssml_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts/ssml"
+)
+
+func TestRender(t *testing.T) {
+	doc := ssml.NewSpeak("en-US",
+		ssml.NewVoice("en-US-AriaNeural",
+			ssml.NewExpressAs("cheerful",
+				ssml.NewProsody(ssml.Text(`Tom & "Jerry" <3`)).
+					WithRate("+10%").
+					WithContour(ssml.ContourPoint{Position: 0, Pitch: "+20Hz"}, ssml.ContourPoint{Position: 60.5, Pitch: "-10%"}),
+			).WithStyleDegree(1.5),
+			ssml.NewBreak(300*time.Millisecond),
+			ssml.NewBreak(0).WithStrength("strong"),
+			ssml.NewParagraph(ssml.NewSentence(
+				ssml.NewEmphasis("moderate", ssml.Text("Due")),
+				ssml.Text(" "),
+				ssml.NewSayAs("date", "2024-05-01").WithFormat("ymd"),
+			)),
+			ssml.NewLang("fr-FR", ssml.NewSub("World Wide Web Consortium", "W3C")),
+			ssml.NewPhoneme("ipa", "təˈmɑːtoʊ", "tomato"),
+		),
+	)
+
+	want := `<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xmlns:mstts="https://www.w3.org/2001/mstts" xml:lang="en-US">` +
+		`<voice name="en-US-AriaNeural">` +
+		`<mstts:express-as style="cheerful" styledegree="1.5">` +
+		`<prosody contour="(0%,+20Hz) (60.5%,-10%)" rate="+10%">Tom &amp; "Jerry" &lt;3</prosody>` +
+		`</mstts:express-as>` +
+		`<break time="300ms"/><break strength="strong"/>` +
+		`<p><s><emphasis level="moderate">Due</emphasis> <say-as interpret-as="date" format="ymd">2024-05-01</say-as></s></p>` +
+		`<lang xml:lang="fr-FR"><sub alias="World Wide Web Consortium">W3C</sub></lang>` +
+		`<phoneme alphabet="ipa" ph="təˈmɑːtoʊ">tomato</phoneme>` +
+		`</voice></speak>`
+	if got := doc.String(); got != want {
+		t.Fatalf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	parsed, err := ssml.ParseString(want)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, doc) {
+		t.Fatalf("ParseString() = %#v, want %#v", parsed, doc)
+	}
+}
+
+func TestParseKeepsUnknownElements(t *testing.T) {
+	const doc = `<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xmlns:mstts="https://www.w3.org/2001/mstts" xml:lang="en-US">
+  <voice name="en-US-GuyNeural">
+    <mstts:silence type="Sentenceboundary" value="200ms"/>
+    <bookmark mark="start"/>Hello
+  </voice>
+</speak>`
+	parsed, err := ssml.ParseString(doc)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	if parsed.Lang != "en-US" {
+		t.Fatalf("Lang = %q", parsed.Lang)
+	}
+	rendered := parsed.String()
+	for _, want := range []string{
+		`<mstts:silence type="Sentenceboundary" value="200ms"/>`,
+		`<bookmark mark="start"/>Hello`,
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Fatalf("String() = %s, want it to contain %s", rendered, want)
+		}
+	}
+}
+
+func TestParseKeepsAttributes(t *testing.T) {
+	const doc = `<speak version="1.1" xmlns="http://www.w3.org/2001/10/synthesis" xmlns:mstts="https://www.w3.org/2001/mstts" xmlns:acme="urn:acme" xml:lang="en-US" acme:id="d1">` +
+		`<voice name="en-US-AriaNeural" effect="eq_car"><!-- intro -->` +
+		`<prosody rate="" acme:mode="x"><acme:pause len="2"/>Hello</prosody>` +
+		`<bookmark mark=""/>` +
+		`</voice></speak>`
+	parsed, err := ssml.ParseString(doc)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	want := `<speak xmlns="http://www.w3.org/2001/10/synthesis" xmlns:mstts="https://www.w3.org/2001/mstts" xml:lang="en-US" version="1.1" xmlns:acme="urn:acme" acme:id="d1">` +
+		`<voice name="en-US-AriaNeural" effect="eq_car"><!-- intro -->` +
+		`<prosody rate="" acme:mode="x"><acme:pause len="2"/>Hello</prosody>` +
+		`<bookmark mark=""/>` +
+		`</voice></speak>`
+	if got := parsed.String(); got != want {
+		t.Fatalf("String() =\n%s\nwant\n%s", got, want)
+	}
+	if voice := parsed.Children[0].(*ssml.Voice); voice.Name != "en-US-AriaNeural" || len(voice.Attrs) != 1 {
+		t.Fatalf("voice = %+v, want the name and the effect attribute", voice)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, doc := range []string{
+		``,
+		`<voice name="x">hi</voice>`,
+		`<speak><voice>`,
+		`<speak xmlns="urn:other">hi</speak>`,
+		`<other:speak xmlns:other="urn:other">hi</other:speak>`,
+		`<speak><prosody contour="up">hi</prosody></speak>`,
+		`<speak><break time="soon"/></speak>`,
+	} {
+		if _, err := ssml.ParseString(doc); err == nil {
+			t.Errorf("ParseString(%q) error = nil, want an error", doc)
+		}
+	}
+}
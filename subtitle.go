@@ -0,0 +1,210 @@
+package edgetts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SubtitleFormat identifies a subtitle file format.
+type SubtitleFormat int
+
+const (
+	// SubtitleSRT is the SubRip format.
+	SubtitleSRT SubtitleFormat = iota
+	// SubtitleWebVTT is the W3C WebVTT format.
+	SubtitleWebVTT
+	// SubtitleLRC is the LRC lyrics format. It only carries start times.
+	SubtitleLRC
+)
+
+const (
+	defaultCueMaxChars    = 42
+	defaultCueMaxDuration = 5 * time.Second
+)
+
+// Cue is one subtitle line.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+type subtitleOption struct {
+	format      SubtitleFormat
+	formatSet   bool
+	maxChars    int
+	maxDuration time.Duration
+}
+
+// SubtitleOption configures subtitle generation.
+type SubtitleOption func(option *subtitleOption)
+
+// WithSubtitleFormat sets the output format. When saving to a file without this option
+// the format is derived from the file extension (.srt, .vtt or .lrc).
+func WithSubtitleFormat(format SubtitleFormat) SubtitleOption {
+	return func(option *subtitleOption) {
+		option.format = format
+		option.formatSet = true
+	}
+}
+
+// WithCueMaxChars limits the number of characters in one cue. The default is 42.
+func WithCueMaxChars(n int) SubtitleOption {
+	return func(option *subtitleOption) {
+		option.maxChars = n
+	}
+}
+
+// WithCueMaxDuration limits how long one cue stays on screen. The default is 5s.
+func WithCueMaxDuration(d time.Duration) SubtitleOption {
+	return func(option *subtitleOption) {
+		option.maxDuration = d
+	}
+}
+
+func newSubtitleOption(opts ...SubtitleOption) *subtitleOption {
+	option := &subtitleOption{}
+	for _, apply := range opts {
+		apply(option)
+	}
+	if option.maxChars <= 0 {
+		option.maxChars = defaultCueMaxChars
+	}
+	if option.maxDuration <= 0 {
+		option.maxDuration = defaultCueMaxDuration
+	}
+	return option
+}
+
+// BuildCues groups word boundaries into readable subtitle cues. A cue is closed when
+// adding the next word would exceed the character or duration limit, or when a new
+// sentence starts if sentence boundaries are present.
+func BuildCues(boundaries []Boundary, opts ...SubtitleOption) []Cue {
+	option := newSubtitleOption(opts...)
+
+	var sentenceStarts []time.Duration
+	for _, b := range boundaries {
+		if b.Kind == BoundarySentence {
+			sentenceStarts = append(sentenceStarts, b.Offset)
+		}
+	}
+
+	var (
+		cues    []Cue
+		current Cue
+		open    bool
+	)
+	flush := func() {
+		if open {
+			cues = append(cues, current)
+		}
+		open = false
+	}
+	for _, b := range boundaries {
+		if b.Kind != BoundaryWord || strings.TrimSpace(b.Text) == "" {
+			continue
+		}
+		// consume sentence starts up to this word; a new sentence closes the current cue
+		startsSentence := false
+		for len(sentenceStarts) > 0 && sentenceStarts[0] <= b.Offset {
+			startsSentence = true
+			sentenceStarts = sentenceStarts[1:]
+		}
+		if open {
+			text := joinCueText(current.Text, b.Text)
+			if startsSentence ||
+				utf8.RuneCountInString(text) > option.maxChars ||
+				b.End()-current.Start > option.maxDuration {
+				flush()
+			} else {
+				current.Text = text
+				current.End = b.End()
+				continue
+			}
+		}
+		current = Cue{Start: b.Offset, End: b.End(), Text: b.Text}
+		open = true
+	}
+	flush()
+	return cues
+}
+
+// joinCueText appends word to text, separating them with a space unless the
+// boundary is between CJK characters or before punctuation.
+func joinCueText(text, word string) string {
+	if text == "" {
+		return word
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	first, _ := utf8.DecodeRuneInString(word)
+	if isCJK(last) || isCJK(first) || unicode.IsPunct(first) {
+		return text + word
+	}
+	return text + " " + word
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// WriteSubtitles writes cues to w in the given format.
+func WriteSubtitles(w io.Writer, cues []Cue, format SubtitleFormat) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case SubtitleSRT:
+		for i, cue := range cues {
+			fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, formatCueTime(cue.Start, ','), formatCueTime(cue.End, ','), cue.Text)
+		}
+	case SubtitleWebVTT:
+		bw.WriteString("WEBVTT\n\n")
+		for _, cue := range cues {
+			fmt.Fprintf(bw, "%s --> %s\n%s\n\n", formatCueTime(cue.Start, '.'), formatCueTime(cue.End, '.'), vttEscaper.Replace(cue.Text))
+		}
+	case SubtitleLRC:
+		for _, cue := range cues {
+			fmt.Fprintf(bw, "[%s]%s\n", formatLRCTime(cue.Start), cue.Text)
+		}
+	default:
+		return fmt.Errorf("%w: %d", ErrUnknownSubtitleFormat, format)
+	}
+	return bw.Flush()
+}
+
+// vttEscaper escapes the characters WebVTT cue text treats as markup.
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// subtitleFormatFromPath derives the format from a file extension, defaulting to SRT.
+func subtitleFormatFromPath(path string) SubtitleFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vtt":
+		return SubtitleWebVTT
+	case ".lrc":
+		return SubtitleLRC
+	default:
+		return SubtitleSRT
+	}
+}
+
+// formatCueTime formats d as HH:MM:SS followed by sep and milliseconds.
+func formatCueTime(d time.Duration, sep byte) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, sep, ms%1000)
+}
+
+// formatLRCTime formats d as MM:SS.xx; minutes are not wrapped at one hour.
+func formatLRCTime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
@@ -0,0 +1,94 @@
+package edgetts
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func word(text string, offset, duration time.Duration) Boundary {
+	return Boundary{Kind: BoundaryWord, Offset: offset, Duration: duration, Text: text}
+}
+
+func TestBuildCuesGroupsWords(t *testing.T) {
+	boundaries := []Boundary{
+		word("Hello", 0, 300*time.Millisecond),
+		word("world", 400*time.Millisecond, 300*time.Millisecond),
+		word("again", 800*time.Millisecond, 300*time.Millisecond),
+	}
+	cues := BuildCues(boundaries, WithCueMaxChars(11))
+	if len(cues) != 2 {
+		t.Fatalf("expected 2 cues, got %+v", cues)
+	}
+	if cues[0].Text != "Hello world" || cues[0].End != 700*time.Millisecond {
+		t.Fatalf("unexpected first cue: %+v", cues[0])
+	}
+	if cues[1].Text != "again" || cues[1].Start != 800*time.Millisecond {
+		t.Fatalf("unexpected second cue: %+v", cues[1])
+	}
+}
+
+func TestBuildCuesSplitsOnDurationAndSentence(t *testing.T) {
+	boundaries := []Boundary{
+		{Kind: BoundarySentence, Offset: 0, Duration: time.Second, Text: "你好。"},
+		word("你好", 0, time.Second),
+		{Kind: BoundarySentence, Offset: time.Second, Duration: 2 * time.Second, Text: "世界很大。"},
+		word("世界", time.Second, time.Second),
+		word("很大", 2*time.Second, time.Second),
+	}
+	cues := BuildCues(boundaries)
+	if len(cues) != 2 || cues[0].Text != "你好" || cues[1].Text != "世界很大" {
+		t.Fatalf("unexpected cues: %+v", cues)
+	}
+
+	cues = BuildCues(boundaries[3:], WithCueMaxDuration(time.Second))
+	if len(cues) != 2 {
+		t.Fatalf("expected duration split, got %+v", cues)
+	}
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	cues := []Cue{{Start: 1500 * time.Millisecond, End: 3*time.Second + 250*time.Millisecond, Text: "hello"}}
+	cases := map[SubtitleFormat]string{
+		SubtitleSRT:    "1\n00:00:01,500 --> 00:00:03,250\nhello\n\n",
+		SubtitleWebVTT: "WEBVTT\n\n00:00:01.500 --> 00:00:03.250\nhello\n\n",
+		SubtitleLRC:    "[00:01.50]hello\n",
+	}
+	for format, want := range cases {
+		var buf bytes.Buffer
+		if err := WriteSubtitles(&buf, cues, format); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Fatalf("format %d: got %q, want %q", format, buf.String(), want)
+		}
+	}
+	if err := WriteSubtitles(&bytes.Buffer{}, cues, SubtitleFormat(99)); !errors.Is(err, ErrUnknownSubtitleFormat) {
+		t.Fatalf("expected ErrUnknownSubtitleFormat, got %v", err)
+	}
+}
+
+func TestWriteSubtitlesEscapesWebVTT(t *testing.T) {
+	cues := []Cue{{Start: 0, End: time.Second, Text: "a < b & c > d"}}
+	cases := map[SubtitleFormat]string{
+		SubtitleSRT:    "1\n00:00:00,000 --> 00:00:01,000\na < b & c > d\n\n",
+		SubtitleWebVTT: "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\na &lt; b &amp; c &gt; d\n\n",
+		SubtitleLRC:    "[00:00.00]a < b & c > d\n",
+	}
+	for format, want := range cases {
+		var buf bytes.Buffer
+		if err := WriteSubtitles(&buf, cues, format); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Fatalf("format %d: got %q, want %q", format, buf.String(), want)
+		}
+	}
+}
+
+func TestSubtitleFormatFromPath(t *testing.T) {
+	if subtitleFormatFromPath("a.VTT") != SubtitleWebVTT || subtitleFormatFromPath("a.lrc") != SubtitleLRC || subtitleFormatFromPath("a.txt") != SubtitleSRT {
+		t.Fatal("unexpected subtitle format detection")
+	}
+}
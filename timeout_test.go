@@ -0,0 +1,100 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts"
+	"github.com/lib-x/edgetts/edgettstest"
+)
+
+func TestCancelInterruptsHungTurn(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{OmitTurnEnd: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := edgetts.New(server.Option()).Bytes(ctx, "hello")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Bytes() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Bytes() returned after %v, want prompt return on cancellation", elapsed)
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	// accepts TCP connections but never answers the websocket handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	client := edgetts.New(
+		edgetts.WithEndpoint("ws://"+listener.Addr().String()+"/edge/v1"),
+		edgetts.WithDialTimeout(50*time.Millisecond),
+	)
+	_, err = client.Bytes(context.Background(), "hello")
+	var handshakeErr *edgetts.HandshakeError
+	if !errors.Is(err, edgetts.ErrDialTimeout) || !errors.As(err, &handshakeErr) {
+		t.Fatalf("Bytes() error = %v, want ErrDialTimeout in a HandshakeError", err)
+	}
+}
+
+func TestFirstByteTimeout(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{Delay: 500 * time.Millisecond})
+
+	client := edgetts.New(server.Option(), edgetts.WithFirstByteTimeout(50*time.Millisecond))
+	_, err := client.Bytes(context.Background(), "hello")
+	var timeoutErr *edgetts.TimeoutError
+	if !errors.Is(err, edgetts.ErrFirstByteTimeout) || !errors.As(err, &timeoutErr) || timeoutErr.RequestID == "" {
+		t.Fatalf("Bytes() error = %v, want ErrFirstByteTimeout with a request ID", err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{OmitTurnEnd: true})
+
+	client := edgetts.New(server.Option(), edgetts.WithIdleTimeout(50*time.Millisecond))
+	_, err := client.Bytes(context.Background(), "hello")
+	if !errors.Is(err, edgetts.ErrIdleTimeout) {
+		t.Fatalf("Bytes() error = %v, want ErrIdleTimeout", err)
+	}
+}
+
+func TestIdleTimeoutIsRetried(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+	server.InjectFaults(edgettstest.Fault{OmitTurnEnd: true})
+
+	client := edgetts.New(server.Option(),
+		edgetts.WithIdleTimeout(50*time.Millisecond),
+		edgetts.WithRetry(1, time.Millisecond),
+	)
+	audio, err := client.Bytes(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	if string(audio) != "hello" {
+		t.Fatalf("audio = %q, want %q", audio, "hello")
+	}
+}
@@ -0,0 +1,19 @@
+package edgetts
+
+import "github.com/lib-x/edgetts/internal/transport"
+
+// Transport opens connections to the synthesis service. The default transport dials
+// the Edge websocket endpoint and honours the proxy and TLS options; a custom one set
+// with WithTransport can route through a connection broker, add instrumentation or
+// replace the network with an in-memory fake.
+type Transport = transport.Transport
+
+// Conn is a message-oriented connection returned by a Transport. *websocket.Conn
+// from github.com/gorilla/websocket satisfies it.
+type Conn = transport.Conn
+
+// Message types exchanged over a Conn.
+const (
+	TextMessage   = transport.TextMessage
+	BinaryMessage = transport.BinaryMessage
+)
@@ -0,0 +1,144 @@
+package edgetts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTransport answers every ssml message with turn.start, one word boundary per
+// word, a single audio frame containing the spoken text and turn.end.
+type fakeTransport struct {
+	mu    sync.Mutex
+	dials int
+	urls  []string
+}
+
+func (t *fakeTransport) Dial(ctx context.Context, url string, header http.Header) (Conn, *http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dials++
+	t.urls = append(t.urls, url)
+	return &fakeConn{incoming: make(chan fakeMessage, 64)}, nil, nil
+}
+
+type fakeMessage struct {
+	messageType int
+	data        []byte
+}
+
+type fakeConn struct {
+	incoming chan fakeMessage
+	closed   bool
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	msg, ok := <-c.incoming
+	if !ok {
+		return 0, nil, io.EOF
+	}
+	return msg.messageType, msg.data, nil
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	headers, body, _ := strings.Cut(string(data), "\r\n\r\n")
+	if !strings.Contains(headers, "Path:ssml") {
+		return nil
+	}
+	var requestID string
+	for _, line := range strings.Split(headers, "\r\n") {
+		if id, ok := strings.CutPrefix(line, "X-RequestId:"); ok {
+			requestID = id
+		}
+	}
+	text := body
+	if start := strings.Index(body, "<prosody"); start >= 0 {
+		text = body[strings.Index(body[start:], ">")+start+1 : strings.Index(body, "</prosody>")]
+	}
+
+	c.incoming <- fakeMessage{TextMessage, []byte("X-RequestId:" + requestID + "\r\nPath:turn.start\r\n\r\n{}")}
+	for i, word := range strings.Fields(text) {
+		metadata := fmt.Sprintf(`{"Metadata":[{"Type":"WordBoundary","Data":{"Offset":%d,"Duration":1000000,"text":{"Text":%q}}}]}`, i*2_000_000, word)
+		c.incoming <- fakeMessage{TextMessage, []byte("X-RequestId:" + requestID + "\r\nPath:audio.metadata\r\n\r\n" + metadata)}
+	}
+	header := []byte("X-RequestId:" + requestID + "\r\nPath:audio\r\n")
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(header)))
+	frame = append(append(frame, header...), strings.TrimSpace(text)...)
+	c.incoming <- fakeMessage{BinaryMessage, frame}
+	c.incoming <- fakeMessage{TextMessage, []byte("X-RequestId:" + requestID + "\r\nPath:turn.end\r\n\r\n{}")}
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	if !c.closed {
+		c.closed = true
+		close(c.incoming)
+	}
+	return nil
+}
+
+func TestWithTransport(t *testing.T) {
+	transport := &fakeTransport{}
+	client := New(WithTransport(transport), WithVoice("en-US-GuyNeural"))
+
+	data, boundaries, err := client.DoWithBoundaries(context.Background(), Text("hello fake world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello fake world" {
+		t.Fatalf("unexpected audio: %q", data)
+	}
+	if len(boundaries) != 3 || boundaries[2].Text != "world" {
+		t.Fatalf("unexpected boundaries: %+v", boundaries)
+	}
+	if transport.dials != 1 || !strings.Contains(transport.urls[0], "Sec-MS-GEC=") {
+		t.Fatalf("unexpected dials: %d %v", transport.dials, transport.urls)
+	}
+}
+
+type failingTransport struct{}
+
+func (failingTransport) Dial(context.Context, string, http.Header) (Conn, *http.Response, error) {
+	return nil, &http.Response{StatusCode: http.StatusServiceUnavailable}, errors.New("broker unavailable")
+}
+
+func TestWithTransportHandshakeError(t *testing.T) {
+	_, err := New(WithTransport(failingTransport{})).Do(context.Background(), Text("hello"))
+	var handshakeErr *HandshakeError
+	if !errors.As(err, &handshakeErr) || handshakeErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected HandshakeError with 503, got %v", err)
+	}
+}
+
+func TestEventsWithTransport(t *testing.T) {
+	client := New(WithTransport(&fakeTransport{}))
+	var (
+		audio bytes.Buffer
+		kinds []string
+	)
+	for event, err := range client.EventSeq(context.Background(), Text("one two")) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch e := event.(type) {
+		case *TurnStart:
+			kinds = append(kinds, "start")
+		case *WordBoundary:
+			kinds = append(kinds, e.Text)
+		case *AudioChunk:
+			audio.Write(e.Data)
+		case *TurnEnd:
+			kinds = append(kinds, "end")
+		}
+	}
+	if strings.Join(kinds, ",") != "start,one,two,end" || audio.String() != "one two" {
+		t.Fatalf("unexpected events: %v %q", kinds, audio.String())
+	}
+}
@@ -1,5 +1,7 @@
 package edgetts
 
+import "strings"
+
 // InputType identifies the input payload type.
 type InputType int
 
@@ -13,6 +15,27 @@ type Request struct {
 	Input   string
 	Type    InputType
 	Options []Option
+	// Spans, if set, replace Input for a text request. See Spans.
+	Spans []Span
+}
+
+// Span is a run of text in Lang, a locale such as en-US. An empty Lang is the language
+// of the voice.
+type Span struct {
+	Lang string
+	Text string
+}
+
+func (r Request) isEmpty() bool {
+	if r.Type == InputText && len(r.Spans) > 0 {
+		for _, span := range r.Spans {
+			if strings.TrimSpace(span.Text) != "" {
+				return false
+			}
+		}
+		return true
+	}
+	return strings.TrimSpace(r.Input) == ""
 }
 
 // Text creates a text synthesis request.
@@ -20,6 +43,20 @@ func Text(input string, opts ...Option) Request {
 	return Request{Input: input, Type: InputText, Options: opts}
 }
 
+// Spans creates a text request from runs of text in different languages, such as
+// English terms in a Chinese text. With a multilingual voice, one whose name ends in
+// MultilingualNeural like en-US-AvaMultilingualNeural, spans in another language than
+// the voice are wrapped in <lang xml:lang> so that they are read with the right
+// pronunciation. Other voices read every span in their own language. Normalizers run
+// on each span separately.
+func Spans(spans []Span, opts ...Option) Request {
+	var input strings.Builder
+	for _, span := range spans {
+		input.WriteString(span.Text)
+	}
+	return Request{Input: input.String(), Type: InputText, Options: opts, Spans: spans}
+}
+
 // SSML creates an SSML synthesis request.
 func SSML(input string, opts ...Option) Request {
 	return Request{Input: input, Type: InputSSML, Options: opts}
@@ -3,18 +3,20 @@ package edgetts
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/lib-x/edgetts/internal/businessConsts"
 )
 
-var (
-	getVoiceHeader http.Header
-	theaderOnce    = &sync.Once{}
-)
-
 type Voice struct {
 	Name           string `json:"Name"`
 	ShortName      string `json:"ShortName"`
@@ -27,60 +29,272 @@ type Voice struct {
 	VoiceTag       VoiceTag `json:"VoiceTag"`
 }
 
+// UnmarshalJSON decodes a voice of the service voice list. The list has no language
+// field, so a missing Language is taken from the Locale, such as "en" for "en-US".
+func (v *Voice) UnmarshalJSON(data []byte) error {
+	type voice Voice
+	if err := json.Unmarshal(data, (*voice)(v)); err != nil {
+		return err
+	}
+	if v.Language == "" {
+		v.Language, _, _ = strings.Cut(v.Locale, "-")
+	}
+	return nil
+}
+
 type VoiceTag struct {
 	ContentCategories  []string `json:"ContentCategories"`
 	VoicePersonalities []string `json:"VoicePersonalities"`
 }
 
+// DefaultVoiceCacheTTL is how long a VoiceManager serves voices before refreshing them.
+const DefaultVoiceCacheTTL = 24 * time.Hour
+
+// maxVoiceRefreshTime bounds a background refresh.
+const maxVoiceRefreshTime = time.Minute
+
+// VoiceManager lists voices from a VoiceSource and caches them in memory and, with
+// WithVoiceCacheFile, on disk. Once the cache is older than its TTL, the cached voices
+// are still returned while they are refreshed in the background. When the service
+// cannot be reached and nothing is cached yet, the fallback source set with
+// WithVoiceFallback, if any, answers instead. A VoiceManager is safe for concurrent use
+// and meant to be shared: clients without endpoint or network options share one.
 type VoiceManager struct {
-	client *http.Client
+	source   VoiceSource
+	fallback VoiceSource
+	ttl      time.Duration
+	file     string
+
+	// load serializes the listings made while nothing is cached.
+	load sync.Mutex
+
+	mu         sync.Mutex
+	voices     []Voice
+	fetched    time.Time
+	fileRead   bool
+	refreshing bool
+}
+
+// VoiceManagerOption configures a VoiceManager.
+type VoiceManagerOption func(m *VoiceManager)
+
+// WithVoiceSource sets where voices are listed from. The default is RemoteVoices().
+func WithVoiceSource(source VoiceSource) VoiceManagerOption {
+	return func(m *VoiceManager) {
+		m.source = source
+	}
+}
+
+// WithVoiceFallback sets the source used when the service cannot be reached and no
+// voices are cached, such as a VoiceFile shipped with the application. There is no
+// fallback by default.
+func WithVoiceFallback(source VoiceSource) VoiceManagerOption {
+	return func(m *VoiceManager) {
+		m.fallback = source
+	}
+}
+
+// WithVoiceCacheTTL sets how long voices are served before they are refreshed in the
+// background. The default is DefaultVoiceCacheTTL; 0 disables caching, so every listing
+// asks the source.
+func WithVoiceCacheTTL(ttl time.Duration) VoiceManagerOption {
+	return func(m *VoiceManager) {
+		m.ttl = ttl
+	}
+}
+
+// WithVoiceCacheFile also caches voices in the JSON file at path, so that they survive
+// restarts. The modification time of the file tells how old they are, and a file older
+// than the TTL is still used while the voices are refreshed, or when the service cannot
+// be reached.
+func WithVoiceCacheFile(path string) VoiceManagerOption {
+	return func(m *VoiceManager) {
+		m.file = path
+	}
+}
+
+// NewVoiceManager creates a voice manager listing voices from the service with the
+// built-in defaults, unless WithVoiceSource is used.
+func NewVoiceManager(opts ...VoiceManagerOption) *VoiceManager {
+	m := &VoiceManager{
+		ttl: DefaultVoiceCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.source == nil {
+		m.source = newRemoteVoiceSource(&option{})
+	}
+	return m
 }
 
-func NewVoiceManager() *VoiceManager {
-	theaderOnce.Do(func() {
-		getVoiceHeader = makeVoiceListRequestHeader()
-	})
-	return &VoiceManager{client: &http.Client{}}
+var defaultVoiceManager = sync.OnceValue(func() *VoiceManager { return NewVoiceManager() })
+
+// voiceManagerOf returns the voice manager of a client: the one set with
+// WithVoiceManager, the shared default one, or a new one for the endpoint, proxy, TLS
+// and timeout options of opt.
+func voiceManagerOf(opt *option) *VoiceManager {
+	switch {
+	case opt.VoiceManager != nil:
+		return opt.VoiceManager
+	case !opt.hasVoiceListSettings():
+		return defaultVoiceManager()
+	default:
+		return NewVoiceManager(WithVoiceSource(newRemoteVoiceSource(opt)))
+	}
 }
 
 func (m *VoiceManager) ListVoices() ([]Voice, error) {
-	return m.ListVoicesContext(context.Background())
+	return m.Voices(context.Background())
 }
 
 func (m *VoiceManager) ListVoicesContext(ctx context.Context) ([]Voice, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, businessConsts.VoiceListEndpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("create voice list request: %w", err)
+	return m.Voices(ctx)
+}
+
+// Voices returns the cached voices, listing them from the source if none are cached.
+// The returned slice belongs to the caller.
+func (m *VoiceManager) Voices(ctx context.Context) ([]Voice, error) {
+	if voices, ok := m.cached(ctx); ok {
+		return voices, nil
+	}
+
+	m.load.Lock()
+	defer m.load.Unlock()
+	// another listing may have filled the cache meanwhile
+	if voices, ok := m.cached(ctx); ok {
+		return voices, nil
+	}
+	voices, err := m.source.Voices(ctx)
+	if err == nil {
+		m.store(voices, time.Now())
+		return slices.Clone(voices), nil
+	}
+	if m.fallback == nil || ctx.Err() != nil || !isUnreachable(err) {
+		return nil, err
+	}
+	fallback, fallbackErr := m.fallback.Voices(ctx)
+	if fallbackErr != nil {
+		return nil, err
+	}
+	// cached as already expired, so that the next listing refreshes them
+	m.mu.Lock()
+	if m.ttl > 0 {
+		m.voices, m.fetched = fallback, time.Time{}
+	}
+	m.mu.Unlock()
+	return slices.Clone(fallback), nil
+}
+
+// cached returns the cached voices, reading the cache file the first time, and starts
+// a background refresh once they are older than the TTL.
+func (m *VoiceManager) cached(ctx context.Context) ([]Voice, bool) {
+	if m.ttl <= 0 {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.voices == nil && m.file != "" && !m.fileRead {
+		m.fileRead = true
+		m.readFile()
+	}
+	if m.voices == nil {
+		return nil, false
 	}
-	req.Header = getVoiceHeader.Clone()
+	if time.Since(m.fetched) >= m.ttl && !m.refreshing {
+		m.refreshing = true
+		go m.refresh(context.WithoutCancel(ctx))
+	}
+	return slices.Clone(m.voices), true
+}
 
-	resp, err := m.client.Do(req)
+// refresh lists the voices again, keeping the cached ones if that fails. It gives up
+// after the TTL, or maxVoiceRefreshTime if shorter, so that a stalled service cannot
+// stop later refreshes.
+func (m *VoiceManager) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, min(m.ttl, maxVoiceRefreshTime))
+	defer cancel()
+	voices, err := m.source.Voices(ctx)
+	m.mu.Lock()
+	m.refreshing = false
+	m.mu.Unlock()
+	if err == nil {
+		m.store(voices, time.Now())
+	}
+}
+
+// store caches voices in memory and in the cache file. Failing to write the file only
+// loses the cache across restarts, so it is not reported.
+func (m *VoiceManager) store(voices []Voice, fetched time.Time) {
+	if m.ttl <= 0 {
+		return
+	}
+	m.mu.Lock()
+	m.voices, m.fetched = voices, fetched
+	m.mu.Unlock()
+	if m.file != "" {
+		_ = writeVoiceFile(m.file, voices)
+	}
+}
+
+// readFile loads the cache file, if any, into the memory cache. It is called with mu held.
+func (m *VoiceManager) readFile() {
+	info, err := os.Stat(m.file)
 	if err != nil {
-		return nil, fmt.Errorf("request voices: %w", err)
+		return
+	}
+	voices, err := VoiceFile(m.file).Voices(context.Background())
+	if err != nil || len(voices) == 0 {
+		return
 	}
-	defer resp.Body.Close()
+	m.voices, m.fetched = voices, info.ModTime()
+}
 
-	var voices []Voice
-	if err := json.NewDecoder(resp.Body).Decode(&voices); err != nil {
-		return nil, fmt.Errorf("decode voices: %w", err)
+// writeVoiceFile replaces the file at path with voices, through a temporary file so
+// that readers never see a partial list.
+func writeVoiceFile(path string, voices []Voice) error {
+	data, err := json.MarshalIndent(voices, "", "  ")
+	if err != nil {
+		return err
 	}
-	return voices, nil
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isUnreachable reports whether err means the service could not be reached, as opposed
+// to an answer that was refused or could not be decoded.
+func isUnreachable(err error) bool {
+	var (
+		opErr  *net.OpError
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr) || (errors.As(err, &netErr) && netErr.Timeout())
 }
 
-func makeVoiceListRequestHeader() http.Header {
+func makeVoiceListRequestHeader(browserVersion string) http.Header {
+	major := businessConsts.MajorVersion(browserVersion)
 	header := make(http.Header)
 	header.Set("Content-Type", "application/json")
 	header.Set("Accept", "*/*")
 	header.Set("Authority", "speech.platform.bing.com")
-	header.Set("Sec-CH-UA", fmt.Sprintf(`" Not;A Brand";v="99", "Microsoft Edge";v="%s", "Chromium";v="%s"`,
-		businessConsts.ChromiumMajorVersion,
-		businessConsts.ChromiumMajorVersion,
-	))
+	header.Set("Sec-CH-UA", fmt.Sprintf(`" Not;A Brand";v="99", "Microsoft Edge";v="%s", "Chromium";v="%s"`, major, major))
 	header.Set("Sec-CH-UA-Mobile", "?0")
-	header.Set("User-Agent", fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s.0.0.0 Safari/537.36 Edg/%s.0.0.0",
-		businessConsts.ChromiumMajorVersion,
-		businessConsts.ChromiumMajorVersion,
-	))
+	header.Set("User-Agent", businessConsts.UserAgent(browserVersion))
 	header.Set("Sec-Fetch-Site", "none")
 	header.Set("Sec-Fetch-Mode", "cors")
 	header.Set("Sec-Fetch-Dest", "empty")
@@ -0,0 +1,217 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lib-x/edgetts"
+	"github.com/lib-x/edgetts/edgettstest"
+)
+
+func TestVoicesUseHTTPProxy(t *testing.T) {
+	server := edgettstest.NewServer()
+	defer server.Close()
+
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	client := edgetts.New(server.Option(), edgetts.WithHTTPProxy(proxy.URL))
+	voices, err := client.Voices(context.Background())
+	if err != nil {
+		t.Fatalf("Voices() error = %v", err)
+	}
+	if len(voices) != len(edgettstest.DefaultVoices) {
+		t.Fatalf("voices = %d, want %d", len(voices), len(edgettstest.DefaultVoices))
+	}
+	if proxied.Load() != 1 {
+		t.Fatalf("proxied requests = %d, want 1", proxied.Load())
+	}
+}
+
+func TestVoicesReportHTTPStatus(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "token expired", http.StatusForbidden)
+	}))
+	defer service.Close()
+
+	client := edgetts.New(edgetts.WithVoiceListEndpoint(service.URL))
+	_, err := client.Voices(context.Background())
+	var listErr *edgetts.VoiceListError
+	if !errors.As(err, &listErr) || listErr.StatusCode != http.StatusForbidden || listErr.Body != "token expired" {
+		t.Fatalf("Voices() error = %v, want a VoiceListError with status 403", err)
+	}
+}
+
+// countingSource returns voices named after the number of listings so far.
+func countingSource(calls *atomic.Int32) edgetts.VoiceSource {
+	return edgetts.VoiceSourceFunc(func(ctx context.Context) ([]edgetts.Voice, error) {
+		n := calls.Add(1)
+		return []edgetts.Voice{{ShortName: fmt.Sprintf("en-US-Voice%dNeural", n)}}, nil
+	})
+}
+
+func TestVoiceManagerCaches(t *testing.T) {
+	var calls atomic.Int32
+	manager := edgetts.NewVoiceManager(edgetts.WithVoiceSource(countingSource(&calls)))
+	first := edgetts.New(edgetts.WithVoiceManager(manager))
+	second := edgetts.New(edgetts.WithVoiceManager(manager))
+	for _, client := range []*edgetts.Client{first, second, first} {
+		if _, err := client.Voices(context.Background()); err != nil {
+			t.Fatalf("Voices() error = %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("listings = %d, want 1", calls.Load())
+	}
+
+	uncached := edgetts.NewVoiceManager(edgetts.WithVoiceSource(countingSource(&calls)), edgetts.WithVoiceCacheTTL(0))
+	uncached.ListVoices()
+	uncached.ListVoices()
+	if calls.Load() != 3 {
+		t.Fatalf("listings = %d, want 3", calls.Load())
+	}
+}
+
+func TestVoiceManagerRefreshesInBackground(t *testing.T) {
+	var calls atomic.Int32
+	manager := edgetts.NewVoiceManager(
+		edgetts.WithVoiceSource(countingSource(&calls)),
+		edgetts.WithVoiceCacheTTL(time.Millisecond),
+	)
+	ctx := context.Background()
+	if _, err := manager.Voices(ctx); err != nil {
+		t.Fatalf("Voices() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// expired voices are served while they are refreshed
+	voices, err := manager.Voices(ctx)
+	if err != nil || voices[0].ShortName != "en-US-Voice1Neural" {
+		t.Fatalf("Voices() = %v, %v, want the cached voices", voices, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for time.Now().Before(deadline) {
+		voices, _ = manager.Voices(ctx)
+		if voices[0].ShortName == "en-US-Voice2Neural" {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Voices() = %v after the refresh, want the refreshed voices", voices)
+}
+
+func TestVoiceManagerFallback(t *testing.T) {
+	service := httptest.NewServer(http.NotFoundHandler())
+	endpoint := service.URL
+	service.Close()
+
+	if _, err := edgetts.New(edgetts.WithVoiceListEndpoint(endpoint)).Voices(context.Background()); err == nil {
+		t.Fatal("Voices() without a fallback succeeded")
+	}
+
+	var calls atomic.Int32
+	manager := edgetts.NewVoiceManager(
+		edgetts.WithVoiceSource(edgetts.RemoteVoices(edgetts.WithVoiceListEndpoint(endpoint))),
+		edgetts.WithVoiceFallback(countingSource(&calls)),
+	)
+	voices, err := manager.ListVoices()
+	if err != nil || len(voices) != 1 || voices[0].ShortName != "en-US-Voice1Neural" {
+		t.Fatalf("ListVoices() = %v, %v, want the fallback voices", voices, err)
+	}
+}
+
+func TestVoiceManagerCacheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voices.json")
+	var calls atomic.Int32
+	manager := edgetts.NewVoiceManager(edgetts.WithVoiceSource(countingSource(&calls)), edgetts.WithVoiceCacheFile(path))
+	if _, err := manager.ListVoices(); err != nil {
+		t.Fatalf("ListVoices() error = %v", err)
+	}
+
+	// a new manager, e.g. after a restart, starts from the file
+	restarted := edgetts.NewVoiceManager(edgetts.WithVoiceSource(countingSource(&calls)), edgetts.WithVoiceCacheFile(path))
+	voices, err := restarted.ListVoices()
+	if err != nil || len(voices) != 1 || voices[0].ShortName != "en-US-Voice1Neural" {
+		t.Fatalf("ListVoices() = %v, %v, want the cached voice", voices, err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("listings = %d, want 1", calls.Load())
+	}
+
+	fromFile, err := edgetts.VoiceFile(path).Voices(context.Background())
+	if err != nil || len(fromFile) != 1 {
+		t.Fatalf("VoiceFile() = %v, %v", fromFile, err)
+	}
+}
+
+func TestVoiceLanguageFromLocale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voices.json")
+	data := `[{"ShortName":"en-US-AriaNeural","Locale":"en-US"},{"ShortName":"sr-Latn-RS-NicholasNeural","Locale":"sr-Latn-RS"}]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	voices, err := edgetts.VoiceFile(path).Voices(context.Background())
+	if err != nil {
+		t.Fatalf("VoiceFile() error = %v", err)
+	}
+	english := edgetts.FilterVoices(voices, edgetts.VoiceFilter{Language: "en"})
+	if len(english) != 1 || english[0].ShortName != "en-US-AriaNeural" || voices[1].Language != "sr" {
+		t.Fatalf("voices = %+v, want languages taken from the locales", voices)
+	}
+}
+
+func TestVoiceManagerRefreshSurvivesHungSource(t *testing.T) {
+	var calls atomic.Int32
+	source := edgetts.VoiceSourceFunc(func(ctx context.Context) ([]edgetts.Voice, error) {
+		n := calls.Add(1)
+		if n == 2 {
+			// the first refresh stalls until it is given up
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []edgetts.Voice{{ShortName: fmt.Sprintf("en-US-Voice%dNeural", n)}}, nil
+	})
+	const ttl = 20 * time.Millisecond
+	manager := edgetts.NewVoiceManager(edgetts.WithVoiceSource(source), edgetts.WithVoiceCacheTTL(ttl))
+	ctx := context.Background()
+	if _, err := manager.Voices(ctx); err != nil {
+		t.Fatalf("Voices() error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(ttl / 4)
+		voices, err := manager.Voices(ctx)
+		if err != nil {
+			t.Fatalf("Voices() error = %v", err)
+		}
+		if voices[0].ShortName == "en-US-Voice3Neural" {
+			return
+		}
+	}
+	t.Fatalf("listings = %d, want a refresh after the hung one", calls.Load())
+}
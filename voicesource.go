@@ -0,0 +1,111 @@
+package edgetts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lib-x/edgetts/internal/businessConsts"
+	"github.com/lib-x/edgetts/internal/communicate"
+)
+
+// VoiceSource provides the voice catalog. A VoiceManager caches one; RemoteVoices and
+// VoiceFile are the built-in sources.
+type VoiceSource interface {
+	Voices(ctx context.Context) ([]Voice, error)
+}
+
+// VoiceSourceFunc adapts a function to a VoiceSource.
+type VoiceSourceFunc func(ctx context.Context) ([]Voice, error)
+
+func (f VoiceSourceFunc) Voices(ctx context.Context) ([]Voice, error) { return f(ctx) }
+
+// remoteVoiceSource lists voices from the voice list endpoint of the service.
+type remoteVoiceSource struct {
+	client   *http.Client
+	err      error
+	endpoint string
+	token    string
+	header   http.Header
+}
+
+// RemoteVoices lists voices from the service every time it is asked, using the
+// endpoint, token, browser version, proxy, TLS and timeout options in opts.
+func RemoteVoices(opts ...Option) VoiceSource {
+	opt := &option{}
+	for _, o := range opts {
+		o(opt)
+	}
+	return newRemoteVoiceSource(opt)
+}
+
+// newRemoteVoiceSource creates a remote source from opt, falling back to the built-in
+// defaults.
+func newRemoteVoiceSource(opt *option) *remoteVoiceSource {
+	s := &remoteVoiceSource{
+		endpoint: opt.VoiceListEndpoint,
+		token:    opt.TrustedClientToken,
+	}
+	// a malformed proxy is reported by every listing
+	s.client, s.err = communicate.NewHTTPClient(opt.toInternalOption())
+	if s.endpoint == "" {
+		s.endpoint = businessConsts.VoiceListEndpoint
+	}
+	if s.token == "" {
+		s.token = businessConsts.TrustedClientToken
+	}
+	browserVersion := opt.BrowserVersion
+	if browserVersion == "" {
+		browserVersion = businessConsts.ChromiumFllVersion
+	}
+	s.header = makeVoiceListRequestHeader(browserVersion)
+	return s
+}
+
+func (s *remoteVoiceSource) Voices(ctx context.Context) ([]Voice, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		businessConsts.WithQuery(s.endpoint, "trustedclienttoken", s.token), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create voice list request: %w", err)
+	}
+	req.Header = s.header.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request voices: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &VoiceListError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
+	var voices []Voice
+	if err := json.NewDecoder(resp.Body).Decode(&voices); err != nil {
+		return nil, fmt.Errorf("decode voices: %w", err)
+	}
+	return voices, nil
+}
+
+// VoiceFile reads voices from a JSON file in the format of the service voice list, such
+// as the cache file of WithVoiceCacheFile. The file is read every time.
+func VoiceFile(path string) VoiceSource {
+	return VoiceSourceFunc(func(ctx context.Context) ([]Voice, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var voices []Voice
+		if err := json.Unmarshal(data, &voices); err != nil {
+			return nil, fmt.Errorf("decode voices from %s: %w", path, err)
+		}
+		return voices, nil
+	})
+}